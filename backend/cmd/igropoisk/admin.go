@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"igropoisk_backend/internal/auth"
+	"igropoisk_backend/internal/config"
+	"igropoisk_backend/internal/game"
+	"igropoisk_backend/internal/server"
+	"io"
+	"os"
+	"strings"
+)
+
+//go:embed seed/games.jsonl
+var seedGames []byte
+
+const (
+	reindexUsage          = "reindex"
+	seedUsage             = "seed"
+	importUsage           = "import [-format jsonl|csv] [-report file] <file.jsonl | file.csv | ->"
+	exportUsage           = "export [-o backup.jsonl.gz]"
+	restoreUsage          = "restore <backup.jsonl.gz | ->"
+	createAdminUserUsage  = "create-admin-user -name name -password password [-role admin]"
+	recomputeRatingsUsage = "recompute-ratings"
+	verifyIndexUsage      = "verify-index"
+)
+
+type adminCommand struct {
+	usage string
+	run   func(ctx context.Context, app *server.App, args []string) error
+}
+
+// adminCommands work on the configured storage without starting the HTTP server.
+var adminCommands = map[string]adminCommand{
+	"reindex":           {reindexUsage, runReindex},
+	"seed":              {seedUsage, runSeed},
+	"import":            {importUsage, runImport},
+	"export":            {exportUsage, runExport},
+	"restore":           {restoreUsage, runRestore},
+	"create-admin-user": {createAdminUserUsage, runCreateAdminUser},
+	"recompute-ratings": {recomputeRatingsUsage, runRecomputeRatings},
+	"verify-index":      {verifyIndexUsage, runVerifyIndex},
+}
+
+func runAdmin(ctx context.Context, cfg *config.Config, cmd adminCommand, args []string) error {
+	if err := cfg.ValidateStorage(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	app, err := server.NewApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+	return cmd.run(ctx, app, args)
+}
+
+func newFlagSet(usage string) *flag.FlagSet {
+	name, _, _ := strings.Cut(usage, " ")
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: igropoisk %s\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func runReindex(ctx context.Context, app *server.App, args []string) error {
+	if err := newFlagSet(reindexUsage).Parse(args); err != nil {
+		return err
+	}
+	result, err := app.Services.Games.Reindex(ctx)
+	if result != nil {
+		if err := printJSON(result); err != nil {
+			return err
+		}
+	}
+	if err == nil && result.Failed > 0 {
+		return fmt.Errorf("%d games were not indexed", result.Failed)
+	}
+	return err
+}
+
+func runSeed(ctx context.Context, app *server.App, args []string) error {
+	if err := newFlagSet(seedUsage).Parse(args); err != nil {
+		return err
+	}
+	return importGames(ctx, app.Services.Games, bytes.NewReader(seedGames), game.ImportJSONLines, "")
+}
+
+func runImport(ctx context.Context, app *server.App, args []string) error {
+	fs := newFlagSet(importUsage)
+	format := fs.String("format", "", "jsonl or csv, taken from the file extension when empty")
+	reportPath := fs.String("report", "", "write the per-row report as JSON to this file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("import needs exactly one file")
+	}
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = string(game.ImportJSONLines)
+		if path != "-" {
+			*format = path
+		}
+	}
+	importFormat, err := game.ParseImportFormat(*format)
+	if err != nil {
+		return err
+	}
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	return importGames(ctx, app.Services.Games, r, importFormat, *reportPath)
+}
+
+// importGames prints the rows that were not created and a summary.
+func importGames(ctx context.Context, games game.Service, r io.Reader, format game.ImportFormat, reportPath string) error {
+	records, err := game.DecodeImport(r, format)
+	if err != nil {
+		return err
+	}
+	report, err := games.ImportGames(ctx, records)
+	if err != nil {
+		return err
+	}
+	for _, row := range report.Rows {
+		if row.Status != game.ImportCreated {
+			fmt.Fprintf(os.Stderr, "line %d: %s %q: %s\n", row.Line, row.Status, row.Name, row.Reason)
+		}
+	}
+	if reportPath != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(reportPath, data, 0644); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("created %d, skipped %d, failed %d\n", report.Created, report.Skipped, report.Failed)
+	if report.Failed > 0 {
+		return fmt.Errorf("%d games were not imported", report.Failed)
+	}
+	return nil
+}
+
+func runExport(ctx context.Context, app *server.App, args []string) error {
+	fs := newFlagSet(exportUsage)
+	output := fs.String("o", "-", "output file, - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if app.Services.Backup == nil {
+		return errors.New("export needs postgres storage")
+	}
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	buf := bufio.NewWriter(w)
+	counts, err := app.Services.Backup.Export(ctx, buf)
+	if err != nil {
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %d genres, %d tags, %d games, %d users, %d reviews\n",
+		counts.Genres, counts.Tags, counts.Games, counts.Users, counts.Reviews)
+	return nil
+}
+
+func runRestore(ctx context.Context, app *server.App, args []string) error {
+	fs := newFlagSet(restoreUsage)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("restore needs exactly one file")
+	}
+	if app.Services.Backup == nil {
+		return errors.New("restore needs postgres storage")
+	}
+	var r io.Reader = os.Stdin
+	if path := fs.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	if err := app.Migrate(ctx); err != nil {
+		return err
+	}
+	result, err := app.Services.Backup.Restore(ctx, bufio.NewReader(r))
+	if result != nil {
+		if err := printJSON(result); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "restored users have no passwords, add an admin with create-admin-user")
+	}
+	return err
+}
+
+func runCreateAdminUser(ctx context.Context, app *server.App, args []string) error {
+	fs := newFlagSet(createAdminUserUsage)
+	name := fs.String("name", "", "user name")
+	password := fs.String("password", "", "password, ADMIN_PASSWORD is used when empty")
+	role := fs.String("role", string(auth.RoleAdmin), "user, moderator or admin")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *password == "" {
+		*password = os.Getenv("ADMIN_PASSWORD")
+	}
+	if *name == "" || *password == "" {
+		fs.Usage()
+		return errors.New("name and password are required")
+	}
+	user, err := app.Services.Users.CreateUser(ctx, *name, *password, auth.Role(*role))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created %s %q with id %d\n", user.Role, user.Name, user.ID)
+	return nil
+}
+
+func runRecomputeRatings(ctx context.Context, app *server.App, args []string) error {
+	if err := newFlagSet(recomputeRatingsUsage).Parse(args); err != nil {
+		return err
+	}
+	n, err := app.Services.Reviews.RecomputeRatings(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("recomputed ratings of %d games, the search index catches up through the outbox\n", n)
+	return nil
+}
+
+func runVerifyIndex(ctx context.Context, app *server.App, args []string) error {
+	if err := newFlagSet(verifyIndexUsage).Parse(args); err != nil {
+		return err
+	}
+	diff, err := app.Services.Games.VerifyIndex(ctx)
+	if err != nil {
+		return err
+	}
+	if err := printJSON(diff); err != nil {
+		return err
+	}
+	if !diff.InSync() {
+		return errors.New("search index is out of sync, run reindex")
+	}
+	return nil
+}
@@ -2,72 +2,85 @@ package main
 
 import (
 	"context"
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
+	"errors"
+	"flag"
+	"fmt"
 	"igropoisk_backend/internal/auth"
-	"igropoisk_backend/internal/db/elastic"
-	"igropoisk_backend/internal/db/postgres"
-	"igropoisk_backend/internal/game"
-	"igropoisk_backend/internal/game/genre"
+	"igropoisk_backend/internal/config"
 	"igropoisk_backend/internal/logger"
-	"igropoisk_backend/internal/middleware"
-	"igropoisk_backend/internal/review"
-	"igropoisk_backend/internal/user"
+	"igropoisk_backend/internal/server"
 	"log"
 	"os"
+	"os/signal"
+	"slices"
+	"syscall"
 )
 
 func main() {
-	auth.Init()
-	postgresPool := postgres.GetPool()
-	elasticClient := elastic.NewClient()
-	defer postgresPool.Close()
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "optional YAML config file, environment variables override it")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "usage: igropoisk [-config file] <command>\n\ncommands:\n  serve (default)\n  %s\n", migrateUsage[len("usage: igropoisk "):])
+		names := make([]string, 0, len(adminCommands))
+		for name := range adminCommands {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			fmt.Fprintf(out, "  %s\n", adminCommands[name].usage)
+		}
+		fmt.Fprintln(out, "\nflags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
 
-	userRepo := user.NewPostgresRepository(postgresPool)
-	userService := user.NewService(userRepo)
-	userHandler := user.NewHandler(userService)
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%s", err.Error())
+	}
 
-	gameRepo := game.NewPostgresRepository(postgresPool)
-	genreRepo := genre.NewPostgresRepository(postgresPool)
-	searchRepo := game.NewElasticRepository(elasticClient)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	gameService := game.NewService(gameRepo, genreRepo, searchRepo)
-	gameHandler := game.NewHandler(gameService)
+	switch cmd := flag.Arg(0); cmd {
+	case "", "serve":
+		serve(ctx, cfg)
+	case "migrate":
+		if err := runMigrate(ctx, cfg, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		admin, ok := adminCommands[cmd]
+		if !ok {
+			flag.Usage()
+			os.Exit(2)
+		}
+		if err := runAdmin(ctx, cfg, admin, flag.Args()[1:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(2)
+			}
+			log.Fatal(err)
+		}
+	}
+}
 
-	reviewRepo := review.NewPostgresRepository(postgresPool)
-	reviewService := review.NewService(reviewRepo, gameService)
-	reviewHandler := review.NewHandler(reviewService)
-	r := gin.New()
-	err := logger.InitLogger()
-	defer logger.CloseFile()
-	if err != nil {
-		log.Printf("failed to init logger : %s\n", err.Error())
+func serve(ctx context.Context, cfg *config.Config) {
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("invalid configuration:\n%s", err.Error())
 	}
-	err = searchRepo.SyncWith(context.Background(), gameRepo)
-	if err != nil {
-		logger.Logger.Error("Unable to sync elastic with TS",
-			"error", err)
+	if err := auth.Init(server.AuthOptions(cfg.Auth)); err != nil {
+		log.Fatalf("failed to load signing keys: %s", err.Error())
 	}
-	r.Use(logger.SlogMiddleware())
-	r.Use(gin.Recovery())
-	r.Use(cors.Default()) //temp
-
-	api := r.Group("api")
-	{
-		api.POST("register", userHandler.HandleRegistration)
-		api.POST("login", userHandler.HandleLogin)
-		api.GET("games/:id/reviews", reviewHandler.GetReviewsByGameID)
+	if err := logger.InitLogger(server.LogOptions(cfg.Log)); err != nil {
+		log.Printf("failed to init logger : %s\n", err.Error())
 	}
-	authorizedApi := r.Group("api", middleware.AuthMiddleware())
-	{
-		authorizedApi.GET("games/search", gameHandler.SearchGame)
-		authorizedApi.GET("games/:id", gameHandler.GetGameByID)
-		authorizedApi.GET("games", gameHandler.GetAllGames)
-		authorizedApi.POST("games", gameHandler.AddGame)
-		authorizedApi.DELETE("games/:id", gameHandler.DeleteGameByID)
 
-		authorizedApi.POST("games/:id/reviews", reviewHandler.AddReview)
+	srv, err := server.New(ctx, cfg)
+	if err != nil {
+		logger.CloseFile()
+		log.Fatalf("failed to start: %s", err.Error())
+	}
+	if err := srv.Run(ctx); err != nil {
+		log.Fatalf("server stopped: %s", err.Error())
 	}
-
-	r.Run(":" + os.Getenv("PORT"))
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"igropoisk_backend/internal/config"
+	"igropoisk_backend/internal/db/migrate"
+	"igropoisk_backend/internal/db/postgres"
+	"igropoisk_backend/internal/server"
+	"igropoisk_backend/migrations"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = "usage: igropoisk migrate up | down [steps] | status | baseline <version>"
+
+func runMigrate(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	pool, err := postgres.NewPool(ctx, server.PostgresOptions(cfg.Postgres))
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	migrator, err := migrate.New(pool, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		ran, err := migrator.Up(ctx)
+		for _, m := range ran {
+			fmt.Printf("applied %d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(ran) == 0 {
+			fmt.Println("database is up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return fmt.Errorf("steps must be a positive number, got %q", args[1])
+			}
+		}
+		ran, err := migrator.Down(ctx, steps)
+		for _, m := range ran {
+			fmt.Printf("reverted %d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "baseline":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version <= 0 {
+			return fmt.Errorf("version must be a positive number, got %q", args[1])
+		}
+		marked, err := migrator.Baseline(ctx, version)
+		for _, m := range marked {
+			fmt.Printf("marked %d_%s as applied\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT\tNOTE")
+		for _, s := range statuses {
+			appliedAt, note := "pending", ""
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			if s.Modified {
+				note = "modified after it was applied"
+			}
+			if s.Unknown {
+				note = "unknown to this binary"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, appliedAt, note)
+		}
+		return w.Flush()
+	}
+	return errors.New(migrateUsage)
+}
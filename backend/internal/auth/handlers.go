@@ -0,0 +1,11 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+func HandleJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, JWKS())
+}
@@ -2,38 +2,69 @@ package auth
 
 import (
 	"errors"
+	"fmt"
 	"github.com/golang-jwt/jwt/v5"
-	"os"
 	"time"
 )
 
-var key []byte
+var (
+	// legacy HS256 secret, still accepted for tokens without a kid while migrating
+	key  []byte
+	keys *KeySet
+)
 
-func Init() {
-	key = []byte(os.Getenv("SECRET_KEY"))
+// Options select how tokens are signed.
+type Options struct {
+	SecretKey    string
+	KeysDir      string
+	SigningKeyID string
+}
+
+// Init loads signing keys from opts.KeysDir (see LoadKeySet) and falls back to
+// HS256 with opts.SecretKey when no key directory is configured.
+func Init(opts Options) error {
+	key = []byte(opts.SecretKey)
+	keys = nil
+	if opts.KeysDir != "" {
+		set, err := LoadKeySet(opts.KeysDir, opts.SigningKeyID)
+		if err != nil {
+			return err
+		}
+		keys = set
+	}
+	return nil
 }
 
 type Claims struct {
 	UserID   int    `json:"userID"`
 	Username string `json:"username"`
+	Role     Role   `json:"role"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID int, username string) (string, error) {
-	claims := Claims{userID, username, jwt.RegisteredClaims{
+func GenerateToken(userID int, username string, role Role) (string, error) {
+	jti, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+	claims := Claims{userID, username, role, jwt.RegisteredClaims{
+		ID:        jti,
 		Issuer:    "igropoisk",
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24)),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
 		IssuedAt:  jwt.NewNumericDate(time.Now()),
 	}}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	t, err := token.SignedString(key)
-	return t, err
+	if keys == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString(key)
+	}
+	token := jwt.NewWithClaims(keys.signing.Method, claims)
+	token.Header["kid"] = keys.signing.ID
+	return token.SignedString(keys.signing.Private)
 }
 
 func ParseToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return key, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, verificationKey,
+		jwt.WithValidMethods([]string{"RS256", "EdDSA", "HS256"}))
 
 	if err != nil {
 		return nil, err
@@ -45,3 +76,33 @@ func ParseToken(tokenString string) (*Claims, error) {
 
 	return nil, errors.New("invalid token")
 }
+
+// verificationKey picks the key by kid and refuses tokens whose alg does not match it.
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if token.Method != jwt.SigningMethodHS256 || len(key) == 0 {
+			return nil, errors.New("token has no key id")
+		}
+		return key, nil
+	}
+	if keys == nil {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	k, ok := keys.Lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != k.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+	return k.Public, nil
+}
+
+// JWKS publishes the verification keys; it is empty when only HS256 is configured.
+func JWKS() JWKSet {
+	if keys == nil {
+		return JWKSet{Keys: []JWK{}}
+	}
+	return keys.JWKS()
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Key is one verification key, optionally able to sign.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.PrivateKey // nil for verification-only keys
+	Public  crypto.PublicKey
+}
+
+// KeySet holds every key tokens may be verified with during a rotation;
+// only the signing key issues new tokens.
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// LoadKeySet reads "<kid>.pem" files from dir. Private keys (PKCS#1/PKCS#8, RSA or Ed25519)
+// can sign, public keys (PKIX) are kept for verifying tokens issued before a rotation.
+// signingKeyID may be empty when dir holds exactly one private key.
+func LoadKeySet(dir, signingKeyID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	set := &KeySet{keys: make(map[string]*Key)}
+	var private []*Key
+	for _, path := range paths {
+		key, err := loadKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("load key %s: %w", path, err)
+		}
+		if _, ok := set.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		set.keys[key.ID] = key
+		if key.Private != nil {
+			private = append(private, key)
+		}
+	}
+
+	switch {
+	case signingKeyID != "":
+		key, ok := set.keys[signingKeyID]
+		if !ok || key.Private == nil {
+			return nil, fmt.Errorf("no private key with id %q in %s", signingKeyID, dir)
+		}
+		set.signing = key
+	case len(private) == 1:
+		set.signing = private[0]
+	case len(private) == 0:
+		return nil, fmt.Errorf("no private keys in %s", dir)
+	default:
+		return nil, errors.New("several private keys found, the signing key id must be set")
+	}
+	return set, nil
+}
+
+func loadKey(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	key := &Key{ID: strings.TrimSuffix(filepath.Base(path), ".pem")}
+	var parsed any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+	return key, nil
+}
+
+func (s *KeySet) Lookup(kid string) (*Key, bool) {
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public halves of all keys, ordered by key id.
+func (s *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range s.keys {
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
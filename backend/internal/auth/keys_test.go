@@ -0,0 +1,263 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writePEM(t *testing.T, dir, kid, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testKeys writes an RSA key "rs", an Ed25519 key "ed" and the public half of a
+// retired RSA key "old" into a fresh directory.
+func testKeys(t *testing.T) (dir string, rsaKey *rsa.PrivateKey, edKey ed25519.PrivateKey) {
+	t.Helper()
+	dir = t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, dir, "rs", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	_, edKey, err = ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, dir, "ed", "PRIVATE KEY", der)
+
+	old, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err = x509.MarshalPKIXPublicKey(&old.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, dir, "old", "PUBLIC KEY", der)
+	return dir, rsaKey, edKey
+}
+
+func initAuth(t *testing.T, cfg Options) {
+	t.Helper()
+	if err := Init(cfg); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { key, keys = nil, nil })
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir, _, _ := testKeys(t)
+	single := t.TempDir()
+	data, err := os.ReadFile(filepath.Join(dir, "ed.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(single, "only.pem"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		dir       string
+		signingID string
+		wantKid   string
+		wantErr   string
+	}{
+		{"explicit rsa", dir, "rs", "rs", ""},
+		{"explicit eddsa", dir, "ed", "ed", ""},
+		{"single private key", single, "", "only", ""},
+		{"ambiguous", dir, "", "", "signing key id must be set"},
+		{"unknown id", dir, "nope", "", `no private key with id "nope"`},
+		{"public key cannot sign", dir, "old", "", `no private key with id "old"`},
+		{"empty dir", t.TempDir(), "", "", "no private keys"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := LoadKeySet(tt.dir, tt.signingID)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if set.signing.ID != tt.wantKid {
+				t.Fatalf("signing key = %q, want %q", set.signing.ID, tt.wantKid)
+			}
+		})
+	}
+}
+
+func TestTokenRoundTrip(t *testing.T) {
+	dir, _, _ := testKeys(t)
+	tests := []struct {
+		name    string
+		cfg     Options
+		wantAlg string
+		wantKid string
+	}{
+		{"rs256", Options{KeysDir: dir, SigningKeyID: "rs"}, "RS256", "rs"},
+		{"eddsa", Options{KeysDir: dir, SigningKeyID: "ed"}, "EdDSA", "ed"},
+		{"legacy hs256", Options{SecretKey: "secret"}, "HS256", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			initAuth(t, tt.cfg)
+			signed, err := GenerateToken(7, "alice", RoleAdmin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, _, err := jwt.NewParser().ParseUnverified(signed, &Claims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			kid, _ := token.Header["kid"].(string)
+			if token.Method.Alg() != tt.wantAlg || kid != tt.wantKid {
+				t.Fatalf("header = %v, want alg %s kid %q", token.Header, tt.wantAlg, tt.wantKid)
+			}
+			claims, err := ParseToken(signed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.UserID != 7 || claims.Username != "alice" || claims.Role != RoleAdmin || claims.ID == "" {
+				t.Fatalf("claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestParseTokenRejects(t *testing.T) {
+	dir, rsaKey, edKey := testKeys(t)
+	claims := func() Claims {
+		return Claims{UserID: 1, Username: "mallory", Role: RoleAdmin, RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}}
+	}
+	sign := func(t *testing.T, method jwt.SigningMethod, kid string, signingKey any) string {
+		t.Helper()
+		token := jwt.NewWithClaims(method, claims())
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(signingKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	rsaPublicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		cfg   Options
+		token func(t *testing.T) string
+	}{
+		{"unknown kid", Options{KeysDir: dir, SigningKeyID: "rs"}, func(t *testing.T) string {
+			return sign(t, jwt.SigningMethodRS256, "missing", rsaKey)
+		}},
+		{"eddsa token with an rsa kid", Options{KeysDir: dir, SigningKeyID: "rs"}, func(t *testing.T) string {
+			return sign(t, jwt.SigningMethodEdDSA, "rs", edKey)
+		}},
+		// the classic confusion: HMAC keyed with the published RSA public key
+		{"hs256 token with an rsa kid", Options{KeysDir: dir, SigningKeyID: "rs", SecretKey: "secret"}, func(t *testing.T) string {
+			return sign(t, jwt.SigningMethodHS256, "rs", rsaPublicDER)
+		}},
+		{"kid without a key set", Options{SecretKey: "secret"}, func(t *testing.T) string {
+			return sign(t, jwt.SigningMethodHS256, "rs", []byte("secret"))
+		}},
+		{"kid-less rs256", Options{KeysDir: dir, SigningKeyID: "rs", SecretKey: "secret"}, func(t *testing.T) string {
+			return sign(t, jwt.SigningMethodRS256, "", rsaKey)
+		}},
+		{"kid-less hs256 without a secret", Options{KeysDir: dir, SigningKeyID: "rs"}, func(t *testing.T) string {
+			return sign(t, jwt.SigningMethodHS256, "", []byte(""))
+		}},
+		{"kid-less hs256 with the wrong secret", Options{SecretKey: "secret"}, func(t *testing.T) string {
+			return sign(t, jwt.SigningMethodHS256, "", []byte("guess"))
+		}},
+		{"signed by a different key", Options{KeysDir: dir, SigningKeyID: "rs"}, func(t *testing.T) string {
+			other, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return sign(t, jwt.SigningMethodRS256, "rs", other)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			initAuth(t, tt.cfg)
+			if claims, err := ParseToken(tt.token(t)); err == nil {
+				t.Fatalf("accepted a forged token: %+v", claims)
+			}
+		})
+	}
+
+	t.Run("kid-less hs256 during migration", func(t *testing.T) {
+		initAuth(t, Options{KeysDir: dir, SigningKeyID: "rs", SecretKey: "secret"})
+		if _, err := ParseToken(sign(t, jwt.SigningMethodHS256, "", []byte("secret"))); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("retired verification-only key", func(t *testing.T) {
+		initAuth(t, Options{KeysDir: dir, SigningKeyID: "ed"})
+		if _, err := ParseToken(sign(t, jwt.SigningMethodRS256, "rs", rsaKey)); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestJWKS(t *testing.T) {
+	dir, rsaKey, edKey := testKeys(t)
+	initAuth(t, Options{SecretKey: "secret"})
+	if got := JWKS(); got.Keys == nil || len(got.Keys) != 0 {
+		t.Fatalf("JWKS without keys = %+v, want an empty list", got)
+	}
+
+	initAuth(t, Options{KeysDir: dir, SigningKeyID: "rs"})
+	set := JWKS()
+	if len(set.Keys) != 3 || set.Keys[0].Kid != "ed" || set.Keys[1].Kid != "old" || set.Keys[2].Kid != "rs" {
+		t.Fatalf("JWKS kids = %+v, want ed, old, rs", set.Keys)
+	}
+
+	ed := set.Keys[0]
+	wantX := base64.RawURLEncoding.EncodeToString(edKey.Public().(ed25519.PublicKey))
+	if ed != (JWK{Kty: "OKP", Kid: "ed", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: wantX}) {
+		t.Fatalf("Ed25519 JWK = %+v", ed)
+	}
+
+	rs := set.Keys[2]
+	if rs.Kty != "RSA" || rs.Use != "sig" || rs.Alg != "RS256" || rs.Crv != "" || rs.X != "" {
+		t.Fatalf("RSA JWK = %+v", rs)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(rs.N)
+	if err != nil || new(big.Int).SetBytes(n).Cmp(rsaKey.N) != 0 {
+		t.Fatalf("RSA modulus does not round trip: %v", err)
+	}
+	if rs.E != "AQAB" {
+		t.Fatalf("RSA exponent = %q, want AQAB", rs.E)
+	}
+}
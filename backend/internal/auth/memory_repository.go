@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryRepository keeps tokens in memory for tests and local development.
+type MemoryRepository struct {
+	mu            sync.Mutex
+	refreshTokens []RefreshToken
+	lastID        int
+	revoked       map[string]time.Time
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{revoked: make(map[string]time.Time)}
+}
+
+func (m *MemoryRepository) AddRefreshToken(ctx context.Context, token RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastID++
+	token.ID = m.lastID
+	token.RevokedAt = nil
+	m.refreshTokens = append(m.refreshTokens, token)
+	return nil
+}
+
+func (m *MemoryRepository) GetRefreshTokenByHash(ctx context.Context, hash string) (*RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, token := range m.refreshTokens {
+		if token.TokenHash == hash {
+			return &token, nil
+		}
+	}
+	return nil, fmt.Errorf("GetRefreshTokenByHash: %w", pgx.ErrNoRows)
+}
+
+func (m *MemoryRepository) RevokeRefreshToken(ctx context.Context, id int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.refreshTokens {
+		if m.refreshTokens[i].ID == id && m.refreshTokens[i].RevokedAt == nil {
+			now := time.Now()
+			m.refreshTokens[i].RevokedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MemoryRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for i := range m.refreshTokens {
+		if m.refreshTokens[i].FamilyID == familyID && m.refreshTokens[i].RevokedAt == nil {
+			m.refreshTokens[i].RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revoked[jti] = expiresAt
+	return nil
+}
+
+func (m *MemoryRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, revoked := m.revoked[jti]
+	return revoked, nil
+}
+
+func (m *MemoryRepository) RemoveExpired(ctx context.Context, t time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	before := len(m.refreshTokens) + len(m.revoked)
+	m.refreshTokens = slices.DeleteFunc(m.refreshTokens, func(token RefreshToken) bool {
+		return token.ExpiresAt.Before(t)
+	})
+	maps.DeleteFunc(m.revoked, func(_ string, expiresAt time.Time) bool {
+		return expiresAt.Before(t)
+	})
+	return int64(before - len(m.refreshTokens) - len(m.revoked)), nil
+}
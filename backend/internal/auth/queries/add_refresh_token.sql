@@ -0,0 +1 @@
+INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)
\ No newline at end of file
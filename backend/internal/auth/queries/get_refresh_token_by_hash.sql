@@ -0,0 +1 @@
+SELECT id, user_id, family_id, expires_at, revoked_at FROM refresh_tokens WHERE token_hash = $1
\ No newline at end of file
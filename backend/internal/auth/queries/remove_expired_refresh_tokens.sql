@@ -0,0 +1 @@
+DELETE FROM refresh_tokens WHERE expires_at < $1
\ No newline at end of file
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+//go:embed queries/add_refresh_token.sql
+var addRefreshTokenSQL string
+
+//go:embed queries/get_refresh_token_by_hash.sql
+var getRefreshTokenByHashSQL string
+
+//go:embed queries/revoke_refresh_token.sql
+var revokeRefreshTokenSQL string
+
+//go:embed queries/revoke_refresh_token_family.sql
+var revokeRefreshTokenFamilySQL string
+
+//go:embed queries/revoke_access_token.sql
+var revokeAccessTokenSQL string
+
+//go:embed queries/is_access_token_revoked.sql
+var isAccessTokenRevokedSQL string
+
+//go:embed queries/remove_expired_refresh_tokens.sql
+var removeExpiredRefreshTokensSQL string
+
+//go:embed queries/remove_expired_revoked_tokens.sql
+var removeExpiredRevokedTokensSQL string
+
+type Repository interface {
+	AddRefreshToken(ctx context.Context, token RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, hash string) (*RefreshToken, error)
+	// RevokeRefreshToken reports false if the token had already been revoked.
+	RevokeRefreshToken(ctx context.Context, id int) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	// RemoveExpired deletes refresh tokens and revocations that expired before t.
+	RemoveExpired(ctx context.Context, t time.Time) (int64, error)
+}
+
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresRepository(pool *pgxpool.Pool) Repository {
+	return &PostgresRepository{pool: pool}
+}
+
+func (p *PostgresRepository) AddRefreshToken(ctx context.Context, token RefreshToken) error {
+	_, err := p.pool.Exec(ctx, addRefreshTokenSQL, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("AddRefreshToken: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) GetRefreshTokenByHash(ctx context.Context, hash string) (*RefreshToken, error) {
+	token := &RefreshToken{TokenHash: hash}
+	err := p.pool.QueryRow(ctx, getRefreshTokenByHashSQL, hash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.ExpiresAt,
+		&token.RevokedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("GetRefreshTokenByHash: %w", err)
+	}
+	return token, nil
+}
+
+func (p *PostgresRepository) RevokeRefreshToken(ctx context.Context, id int) (bool, error) {
+	tag, err := p.pool.Exec(ctx, revokeRefreshTokenSQL, id)
+	if err != nil {
+		return false, fmt.Errorf("RevokeRefreshToken: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (p *PostgresRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	_, err := p.pool.Exec(ctx, revokeRefreshTokenFamilySQL, familyID)
+	if err != nil {
+		return fmt.Errorf("RevokeRefreshTokenFamily: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	_, err := p.pool.Exec(ctx, revokeAccessTokenSQL, jti, expiresAt)
+	if err != nil {
+		return fmt.Errorf("RevokeAccessToken: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	err := p.pool.QueryRow(ctx, isAccessTokenRevokedSQL, jti).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("IsAccessTokenRevoked: %w", err)
+	}
+	return revoked, nil
+}
+
+func (p *PostgresRepository) RemoveExpired(ctx context.Context, t time.Time) (int64, error) {
+	var removed int64
+	for _, query := range []string{removeExpiredRefreshTokensSQL, removeExpiredRevokedTokensSQL} {
+		tag, err := p.pool.Exec(ctx, query, t)
+		if err != nil {
+			return removed, fmt.Errorf("RemoveExpired: %w", err)
+		}
+		removed += tag.RowsAffected()
+	}
+	return removed, nil
+}
@@ -0,0 +1,25 @@
+package auth
+
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+func (r Role) Valid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// AtLeast reports whether r grants everything min does; unknown roles grant nothing.
+func (r Role) AtLeast(min Role) bool {
+	return roleRanks[r] > 0 && roleRanks[r] >= roleRanks[min]
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"igropoisk_backend/internal/logger"
+	"time"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, please log in again")
+)
+
+type Service interface {
+	// IssueTokens starts a new refresh token family when familyID is empty.
+	IssueTokens(ctx context.Context, userID int, username string, role Role, familyID string) (*TokenPair, error)
+	// ConsumeRefreshToken revokes a refresh token so it can be exchanged exactly once.
+	// Presenting an already used token revokes its whole family.
+	ConsumeRefreshToken(ctx context.Context, refreshToken string) (*RefreshToken, error)
+	Logout(ctx context.Context, claims *Claims, refreshToken string) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	// RunCleanup deletes expired tokens every tokenCleanupInterval until ctx is cancelled.
+	RunCleanup(ctx context.Context)
+}
+
+// tokenCleanupInterval paces RunCleanup; expired rows are harmless, they only take space.
+const tokenCleanupInterval = time.Hour
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func (s *service) IssueTokens(ctx context.Context, userID int, username string, role Role, familyID string) (*TokenPair, error) {
+	access, err := GenerateToken(userID, username, role)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	if familyID == "" {
+		if familyID, err = randomString(16); err != nil {
+			return nil, err
+		}
+	}
+	err = s.repo.AddRefreshToken(ctx, RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refresh),
+		ExpiresAt: time.Now().Add(RefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{AccessToken: access, RefreshToken: refresh, ExpiresIn: int(AccessTokenTTL.Seconds())}, nil
+}
+
+func (s *service) ConsumeRefreshToken(ctx context.Context, refreshToken string) (*RefreshToken, error) {
+	token, err := s.repo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if token.RevokedAt == nil && time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	consumed, err := s.repo.RevokeRefreshToken(ctx, token.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		logger.Logger.Warn("Refresh token reuse detected, revoking family",
+			"user_id", token.UserID,
+			"family_id", token.FamilyID)
+		if err := s.repo.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	return token, nil
+}
+
+func (s *service) Logout(ctx context.Context, claims *Claims, refreshToken string) error {
+	if claims.ID != "" && claims.ExpiresAt != nil {
+		if err := s.repo.RevokeAccessToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+			return err
+		}
+	}
+	if refreshToken == "" {
+		return nil
+	}
+	token, err := s.repo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidRefreshToken
+		}
+		return err
+	}
+	if token.UserID != claims.UserID {
+		return ErrInvalidRefreshToken
+	}
+	return s.repo.RevokeRefreshTokenFamily(ctx, token.FamilyID)
+}
+
+func (s *service) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	if jti == "" {
+		return false, nil
+	}
+	return s.repo.IsAccessTokenRevoked(ctx, jti)
+}
+
+func (s *service) RunCleanup(ctx context.Context) {
+	ticker := time.NewTicker(tokenCleanupInterval)
+	defer ticker.Stop()
+	for {
+		removed, err := s.repo.RemoveExpired(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			logger.Logger.Warn("Failed to remove expired tokens",
+				"error", err)
+		} else if removed > 0 {
+			logger.Logger.Info("Removed expired tokens",
+				"removed", removed)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRemoveExpired(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	now := time.Now()
+	for i, expiresAt := range []time.Time{now.Add(-time.Hour), now.Add(time.Hour)} {
+		token := RefreshToken{UserID: 1, FamilyID: "family", TokenHash: string(rune('a' + i)), ExpiresAt: expiresAt}
+		if err := repo.AddRefreshToken(ctx, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.RevokeAccessToken(ctx, "old", now.Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RevokeAccessToken(ctx, "new", now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := repo.RemoveExpired(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Fatalf("removed %d, want 2", removed)
+	}
+	if _, err := repo.GetRefreshTokenByHash(ctx, "a"); err == nil {
+		t.Fatal("expired refresh token is still stored")
+	}
+	// ids stay stable after a removal
+	if consumed, err := repo.RevokeRefreshToken(ctx, 2); err != nil || !consumed {
+		t.Fatalf("RevokeRefreshToken(2) = %v, %v", consumed, err)
+	}
+	if revoked, _ := repo.IsAccessTokenRevoked(ctx, "new"); !revoked {
+		t.Fatal("unexpired revocation was removed")
+	}
+}
@@ -0,0 +1,266 @@
+package backup
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// An archive is gzipped JSON Lines: a header, the records of every table in dependency
+// order and an end record with the counts, so that a truncated archive is detected.
+const (
+	formatName = "igropoisk-backup"
+	// FormatVersion changes with every incompatible change of the records; Restore
+	// reads archives from MinFormatVersion on and upgrades their records.
+	FormatVersion    = 2
+	MinFormatVersion = 1
+)
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported backup version")
+	ErrTruncated          = errors.New("backup is truncated")
+)
+
+type kind string
+
+const (
+	kindHeader kind = "header"
+	kindGenre  kind = "genre"
+	kindTag    kind = "tag"
+	kindGame   kind = "game"
+	kindUser   kind = "user"
+	kindReview kind = "review"
+	kindEnd    kind = "end"
+)
+
+// rank orders the records: every record only references records of a lower rank.
+var rank = map[kind]int{kindGenre: 1, kindTag: 2, kindGame: 3, kindUser: 4, kindReview: 5}
+
+type Header struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Counts struct {
+	Genres  int `json:"genres"`
+	Tags    int `json:"tags"`
+	Games   int `json:"games"`
+	Users   int `json:"users"`
+	Reviews int `json:"reviews"`
+}
+
+type Genre struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type Tag struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// Game leaves out the rating, which is recomputed from the reviews.
+type Game struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+	GenreIDs    []int  `json:"genre_ids"`
+	TagIDs      []int  `json:"tag_ids"`
+}
+
+// gameV1 is a game of a version 1 archive, from before games had several genres and tags.
+type gameV1 struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+	GenreID     int    `json:"genre_id"`
+}
+
+func (g gameV1) upgrade() Game {
+	genreIDs := []int{}
+	if g.GenreID != 0 {
+		genreIDs = append(genreIDs, g.GenreID)
+	}
+	return Game{ID: g.ID, Name: g.Name, Description: g.Description, ImageURL: g.ImageURL, GenreIDs: genreIDs, TagIDs: []int{}}
+}
+
+// User has no password hash; restored users have to be given a new password.
+type User struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Review struct {
+	ID          int     `json:"id"`
+	GameID      int     `json:"game_id"`
+	UserID      int     `json:"user_id"`
+	Rating      int     `json:"rating"`
+	Description *string `json:"description"`
+}
+
+type record struct {
+	Kind kind            `json:"kind"`
+	Data json.RawMessage `json:"data"`
+}
+
+type Writer struct {
+	gz     *gzip.Writer
+	enc    *json.Encoder
+	last   kind
+	counts Counts
+}
+
+// NewWriter writes the header; Close must be called to complete the archive.
+func NewWriter(w io.Writer) (*Writer, error) {
+	gz := gzip.NewWriter(w)
+	bw := &Writer{gz: gz, enc: json.NewEncoder(gz)}
+	err := bw.write(kindHeader, Header{Format: formatName, Version: FormatVersion, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return nil, err
+	}
+	return bw, nil
+}
+
+func (w *Writer) write(k kind, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return w.enc.Encode(record{Kind: k, Data: data})
+}
+
+// Write adds a Genre, Tag, Game, User or Review. Records must be written in that order.
+func (w *Writer) Write(v any) error {
+	var k kind
+	switch v.(type) {
+	case Genre:
+		k, w.counts.Genres = kindGenre, w.counts.Genres+1
+	case Tag:
+		k, w.counts.Tags = kindTag, w.counts.Tags+1
+	case Game:
+		k, w.counts.Games = kindGame, w.counts.Games+1
+	case User:
+		k, w.counts.Users = kindUser, w.counts.Users+1
+	case Review:
+		k, w.counts.Reviews = kindReview, w.counts.Reviews+1
+	default:
+		return fmt.Errorf("cannot back up %T", v)
+	}
+	if rank[k] < rank[w.last] {
+		return fmt.Errorf("%s written after %s", k, w.last)
+	}
+	w.last = k
+	return w.write(k, v)
+}
+
+// Close writes the end record and flushes the archive, it does not close the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.write(kindEnd, w.counts); err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
+
+func (w *Writer) Counts() Counts {
+	return w.counts
+}
+
+type Reader struct {
+	Header Header
+
+	gz     *gzip.Reader
+	dec    *json.Decoder
+	last   kind
+	counts Counts
+}
+
+// NewReader reads and checks the header.
+func NewReader(r io.Reader) (*Reader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	br := &Reader{gz: gz, dec: json.NewDecoder(gz)}
+	var rec record
+	if err := br.dec.Decode(&rec); err != nil || rec.Kind != kindHeader {
+		return nil, errors.New("not a backup archive: no header")
+	}
+	if err := json.Unmarshal(rec.Data, &br.Header); err != nil || br.Header.Format != formatName {
+		return nil, errors.New("not a backup archive: invalid header")
+	}
+	if br.Header.Version < MinFormatVersion || br.Header.Version > FormatVersion {
+		return nil, fmt.Errorf("%w %d, this binary reads versions %d to %d",
+			ErrUnsupportedVersion, br.Header.Version, MinFormatVersion, FormatVersion)
+	}
+	return br, nil
+}
+
+// Next returns the next Genre, Tag, Game, User or Review, and io.EOF after the end record once
+// the counts are checked.
+func (r *Reader) Next() (any, error) {
+	var rec record
+	if err := r.dec.Decode(&rec); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrTruncated
+		}
+		return nil, fmt.Errorf("invalid backup record: %w", err)
+	}
+	if rec.Kind == kindEnd {
+		var want Counts
+		if err := json.Unmarshal(rec.Data, &want); err != nil {
+			return nil, fmt.Errorf("invalid end record: %w", err)
+		}
+		if want != r.counts {
+			return nil, fmt.Errorf("%w: the archive has %+v, expected %+v", ErrTruncated, r.counts, want)
+		}
+		return nil, io.EOF
+	}
+
+	var (
+		v   any
+		err error
+	)
+	switch rec.Kind {
+	case kindGenre:
+		v, err = decode[Genre](rec.Data, &r.counts.Genres)
+	case kindTag:
+		v, err = decode[Tag](rec.Data, &r.counts.Tags)
+	case kindGame:
+		if r.Header.Version == 1 {
+			var g gameV1
+			g, err = decode[gameV1](rec.Data, &r.counts.Games)
+			v = g.upgrade()
+			break
+		}
+		v, err = decode[Game](rec.Data, &r.counts.Games)
+	case kindUser:
+		v, err = decode[User](rec.Data, &r.counts.Users)
+	case kindReview:
+		v, err = decode[Review](rec.Data, &r.counts.Reviews)
+	default:
+		return nil, fmt.Errorf("unknown backup record %q", rec.Kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s record: %w", rec.Kind, err)
+	}
+	if rank[rec.Kind] < rank[r.last] {
+		return nil, fmt.Errorf("%s record after %s records", rec.Kind, r.last)
+	}
+	r.last = rec.Kind
+	return v, nil
+}
+
+func decode[T any](data []byte, count *int) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+	*count++
+	return v, err
+}
@@ -0,0 +1,145 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeArchive(t *testing.T, records ...any) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range records {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readArchive(data []byte) ([]any, error) {
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var records []any
+	for {
+		v, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, v)
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	text := "Great"
+	want := []any{
+		Genre{ID: 1, Name: "RPG"},
+		Genre{ID: 2, Name: "Open World"},
+		Tag{ID: 1, Name: "dragons"},
+		Game{ID: 3, Name: "Skyrim", Description: "Dragons", ImageURL: "img.png", GenreIDs: []int{1, 2}, TagIDs: []int{1}},
+		Game{ID: 4, Name: "Doom", Description: "Demons", ImageURL: "img.png", GenreIDs: []int{}, TagIDs: []int{}},
+		User{ID: 7, Name: "alice", Role: "admin", CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		Review{ID: 9, GameID: 3, UserID: 7, Rating: 9, Description: &text},
+		Review{ID: 10, GameID: 3, UserID: 7, Rating: 4},
+	}
+	got, err := readArchive(writeArchive(t, want...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("read %+v, want %+v", got, want)
+	}
+}
+
+func TestArchiveDetectsTruncation(t *testing.T) {
+	data := writeArchive(t, Genre{ID: 1, Name: "RPG"}, Genre{ID: 2, Name: "FPS"})
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// drop the end record, then the last genre too
+	lines := bytes.SplitAfter(plain, []byte("\n"))
+	for _, n := range []int{len(lines) - 2, len(lines) - 3} {
+		var cut bytes.Buffer
+		zw := gzip.NewWriter(&cut)
+		zw.Write(bytes.Join(lines[:n], nil))
+		zw.Close()
+		if _, err := readArchive(cut.Bytes()); !errors.Is(err, ErrTruncated) {
+			t.Fatalf("with %d lines: err = %v, want ErrTruncated", n, err)
+		}
+	}
+}
+
+func TestArchiveOrder(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(Game{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(Genre{ID: 1}); err == nil {
+		t.Fatal("wrote a genre after a game")
+	}
+}
+
+func gzipped(t *testing.T, plain []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestArchiveReadsVersion1(t *testing.T) {
+	plain, err := os.ReadFile("testdata/backup_v1.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := readArchive(gzipped(t, plain))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []any{
+		Genre{ID: 1, Name: "RPG"},
+		Game{ID: 3, Name: "Skyrim", Description: "Dragons", ImageURL: "img.png", GenreIDs: []int{1}, TagIDs: []int{}},
+		User{ID: 7, Name: "alice", Role: "admin", CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		Review{ID: 9, GameID: 3, UserID: 7, Rating: 9},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("read %+v, want %+v", got, want)
+	}
+
+	future := bytes.Replace(plain, []byte(`"version":1`), []byte(`"version":3`), 1)
+	if _, err := readArchive(gzipped(t, future)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("err = %v, want ErrUnsupportedVersion", err)
+	}
+}
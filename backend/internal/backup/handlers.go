@@ -0,0 +1,34 @@
+package backup
+
+import (
+	"github.com/gin-gonic/gin"
+	"igropoisk_backend/internal/logger"
+	"net/http"
+	"time"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Export streams the archive. Restore is only offered by the CLI: it needs an empty
+// database, which has no admin to authorize the request.
+func (h *Handler) Export(c *gin.Context) {
+	// a large catalog takes longer than the server's write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		logger.Logger.Warn("Failed to lift the write deadline for an export",
+			"error", err)
+	}
+	filename := "igropoisk-" + time.Now().UTC().Format("20060102-150405") + ".jsonl.gz"
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+	// the status is already sent, a failed export ends without its end record
+	if _, err := h.service.Export(c.Request.Context(), c.Writer); err != nil {
+		c.Abort()
+	}
+}
@@ -0,0 +1,9 @@
+SELECT
+    game.id,
+    game.name,
+    COALESCE(game.description, ''),
+    game.image_url,
+    ARRAY(SELECT genre_id FROM game_genres WHERE game_id = game.id ORDER BY genre_id),
+    ARRAY(SELECT tag_id FROM game_tags WHERE game_id = game.id ORDER BY tag_id)
+FROM games game
+ORDER BY game.id
\ No newline at end of file
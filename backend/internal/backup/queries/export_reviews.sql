@@ -0,0 +1 @@
+SELECT id, game_id, user_id, rating, description FROM reviews ORDER BY id
\ No newline at end of file
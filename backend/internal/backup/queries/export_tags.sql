@@ -0,0 +1 @@
+SELECT id, name FROM tags ORDER BY id
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT id, name, role, COALESCE(created_at, now()) FROM users ORDER BY id
\ No newline at end of file
@@ -0,0 +1,5 @@
+SELECT EXISTS (SELECT 1 FROM genres)
+    OR EXISTS (SELECT 1 FROM tags)
+    OR EXISTS (SELECT 1 FROM games)
+    OR EXISTS (SELECT 1 FROM users)
+    OR EXISTS (SELECT 1 FROM reviews)
\ No newline at end of file
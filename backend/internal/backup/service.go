@@ -0,0 +1,65 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"igropoisk_backend/internal/game"
+	"igropoisk_backend/internal/logger"
+	"igropoisk_backend/internal/middleware"
+	"io"
+)
+
+type Service interface {
+	Export(ctx context.Context, w io.Writer) (Counts, error)
+	// Restore fills an empty database from an archive and rebuilds the search index.
+	Restore(ctx context.Context, r io.Reader) (*RestoreResult, error)
+}
+
+type RestoreResult struct {
+	Header  Header              `json:"header"`
+	Counts  Counts              `json:"counts"`
+	Reindex *game.ReindexResult `json:"reindex,omitempty"`
+}
+
+type service struct {
+	store Store
+	games game.Service
+}
+
+func NewService(store Store, games game.Service) Service {
+	return &service{store: store, games: games}
+}
+
+func (s *service) Export(ctx context.Context, w io.Writer) (Counts, error) {
+	archive, err := NewWriter(w)
+	if err == nil {
+		err = s.store.Export(ctx, archive)
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err != nil {
+		logger.Logger.Error("Failed to export backup",
+			"user_id", ctx.Value(middleware.UserIDKey),
+			"error", err)
+		return Counts{}, errors.New("failed to export backup")
+	}
+	return archive.Counts(), nil
+}
+
+func (s *service) Restore(ctx context.Context, r io.Reader) (*RestoreResult, error) {
+	archive, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	result := &RestoreResult{Header: archive.Header}
+	result.Counts, err = s.store.Restore(ctx, archive)
+	if err != nil {
+		logger.Logger.Error("Failed to restore backup",
+			"error", err)
+		return nil, err
+	}
+	// the index is rebuilt even when it fails, the data is already committed
+	result.Reindex, err = s.games.Reindex(ctx)
+	return result, err
+}
@@ -0,0 +1,206 @@
+package backup
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"io"
+)
+
+//go:embed queries/export_genres.sql
+var exportGenresSQL string
+
+//go:embed queries/export_tags.sql
+var exportTagsSQL string
+
+//go:embed queries/export_games.sql
+var exportGamesSQL string
+
+//go:embed queries/export_users.sql
+var exportUsersSQL string
+
+//go:embed queries/export_reviews.sql
+var exportReviewsSQL string
+
+//go:embed queries/has_data.sql
+var hasDataSQL string
+
+//go:embed queries/reset_sequences.sql
+var resetSequencesSQL string
+
+var ErrNotEmpty = errors.New("the database is not empty, restore only into a freshly migrated database")
+
+type Store interface {
+	// Export writes every table to w from a single snapshot.
+	Export(ctx context.Context, w *Writer) error
+	// Restore loads r into empty tables in one transaction, keeping the ids.
+	Restore(ctx context.Context, r *Reader) (Counts, error)
+}
+
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresStore(pool *pgxpool.Pool) Store {
+	return &PostgresStore{pool: pool}
+}
+
+func (p *PostgresStore) Export(ctx context.Context, w *Writer) error {
+	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("Export: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		genre  Genre
+		tag    Tag
+		game   Game
+		user   User
+		review Review
+	)
+	tables := []struct {
+		name string
+		sql  string
+		scan []any
+		row  func() any
+	}{
+		{"genres", exportGenresSQL, []any{&genre.ID, &genre.Name}, func() any { return genre }},
+		{"tags", exportTagsSQL, []any{&tag.ID, &tag.Name}, func() any { return tag }},
+		{"games", exportGamesSQL, []any{&game.ID, &game.Name, &game.Description, &game.ImageURL, &game.GenreIDs, &game.TagIDs}, func() any { return game }},
+		{"users", exportUsersSQL, []any{&user.ID, &user.Name, &user.Role, &user.CreatedAt}, func() any { return user }},
+		{"reviews", exportReviewsSQL, []any{&review.ID, &review.GameID, &review.UserID, &review.Rating, &review.Description}, func() any { return review }},
+	}
+	for _, table := range tables {
+		rows, err := tx.Query(ctx, table.sql)
+		if err != nil {
+			return fmt.Errorf("Export %s: %w", table.name, err)
+		}
+		_, err = pgx.ForEachRow(rows, table.scan, func() error {
+			return w.Write(table.row())
+		})
+		if err != nil {
+			return fmt.Errorf("Export %s: %w", table.name, err)
+		}
+	}
+	return nil
+}
+
+// copyBatchSize bounds the rows buffered for one COPY.
+const copyBatchSize = 5000
+
+// restoreTables are the COPY targets of each record type; a game also fills the
+// game_genres and game_tags join tables.
+var restoreTables = map[string][]string{
+	"genres":      {"id", "name"},
+	"tags":        {"id", "name"},
+	"games":       {"id", "name", "description", "image_url"},
+	"game_genres": {"game_id", "genre_id"},
+	"game_tags":   {"game_id", "tag_id"},
+	"users":       {"id", "name", "password_hash", "role", "created_at"},
+	"reviews":     {"id", "game_id", "user_id", "rating", "description"},
+}
+
+func (p *PostgresStore) Restore(ctx context.Context, r *Reader) (Counts, error) {
+	var counts Counts
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return counts, fmt.Errorf("Restore: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var hasData bool
+	if err := tx.QueryRow(ctx, hasDataSQL).Scan(&hasData); err != nil {
+		return counts, fmt.Errorf("Restore: %w", err)
+	}
+	if hasData {
+		return counts, ErrNotEmpty
+	}
+
+	copyRows := func(table string, rows [][]any) error {
+		if len(rows) == 0 {
+			return nil
+		}
+		_, err := tx.CopyFrom(ctx, pgx.Identifier{table}, restoreTables[table], pgx.CopyFromRows(rows))
+		if err != nil {
+			return fmt.Errorf("Restore %s: %w", table, err)
+		}
+		return nil
+	}
+	var (
+		table                string
+		batch                [][]any
+		gameGenres, gameTags [][]any // links of the games in batch
+	)
+	// flush copies the links after their games
+	flush := func() error {
+		if err := copyRows(table, batch); err != nil {
+			return err
+		}
+		if err := copyRows("game_genres", gameGenres); err != nil {
+			return err
+		}
+		if err := copyRows("game_tags", gameTags); err != nil {
+			return err
+		}
+		batch, gameGenres, gameTags = batch[:0], gameGenres[:0], gameTags[:0]
+		return nil
+	}
+	for {
+		v, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return counts, err
+		}
+		var next string
+		var row []any
+		switch v := v.(type) {
+		case Genre:
+			next, row = "genres", []any{v.ID, v.Name}
+			counts.Genres++
+		case Tag:
+			next, row = "tags", []any{v.ID, v.Name}
+			counts.Tags++
+		case Game:
+			next, row = "games", []any{v.ID, v.Name, v.Description, v.ImageURL}
+			counts.Games++
+		case User:
+			// an empty hash never matches a password
+			next, row = "users", []any{v.ID, v.Name, "", v.Role, v.CreatedAt}
+			counts.Users++
+		case Review:
+			next, row = "reviews", []any{v.ID, v.GameID, v.UserID, v.Rating, v.Description}
+			counts.Reviews++
+		}
+		if next != table || len(batch) == copyBatchSize {
+			if err := flush(); err != nil {
+				return counts, err
+			}
+			table = next
+		}
+		batch = append(batch, row)
+		if v, ok := v.(Game); ok {
+			for _, id := range v.GenreIDs {
+				gameGenres = append(gameGenres, []any{v.ID, id})
+			}
+			for _, id := range v.TagIDs {
+				gameTags = append(gameTags, []any{v.ID, id})
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return counts, err
+	}
+	if _, err := tx.Exec(ctx, resetSequencesSQL); err != nil {
+		return counts, fmt.Errorf("Restore sequences: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return counts, fmt.Errorf("Restore: %w", err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,95 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"igropoisk_backend/internal/db/migrate"
+	"igropoisk_backend/migrations"
+	"os"
+	"testing"
+	"time"
+)
+
+// testStore migrates a fresh schema of TEST_DB_POSTGRES_URL, which is dropped afterwards.
+func testStore(t *testing.T) (*PostgresStore, *pgxpool.Pool) {
+	t.Helper()
+	url := os.Getenv("TEST_DB_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_DB_POSTGRES_URL is not set")
+	}
+	ctx := context.Background()
+	schema := fmt.Sprintf("backup_test_%d", time.Now().UnixNano())
+	admin, err := pgx.Connect(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close(ctx)
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if conn, err := pgx.Connect(ctx, url); err == nil {
+			conn.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE")
+			conn.Close(ctx)
+		}
+	})
+
+	cfg, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema + ",public"
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	migrator, err := migrate.New(pool, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return &PostgresStore{pool: pool}, pool
+}
+
+func TestRestoreOnlyIntoEmptyDatabase(t *testing.T) {
+	store, pool := testStore(t)
+	ctx := context.Background()
+	data := writeArchive(t,
+		Genre{ID: 1, Name: "RPG"},
+		Tag{ID: 2, Name: "dragons"},
+		Game{ID: 3, Name: "Skyrim", Description: "Dragons", ImageURL: "img.png", GenreIDs: []int{1}, TagIDs: []int{2}},
+		User{ID: 7, Name: "alice", Role: "admin", CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		Review{ID: 9, GameID: 3, UserID: 7, Rating: 9},
+	)
+
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts, err := store.Restore(ctx, r)
+	if err != nil {
+		t.Fatalf("restore into a freshly migrated database: %v", err)
+	}
+	if counts != (Counts{Genres: 1, Tags: 1, Games: 1, Users: 1, Reviews: 1}) {
+		t.Fatalf("counts = %+v", counts)
+	}
+	var tagID int
+	if err := pool.QueryRow(ctx, "SELECT tag_id FROM game_tags WHERE game_id = 3").Scan(&tagID); err != nil || tagID != 2 {
+		t.Fatalf("restored game tag = %d, %v; want 2", tagID, err)
+	}
+
+	r, err = NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Restore(ctx, r); !errors.Is(err, ErrNotEmpty) {
+		t.Fatalf("restore into a restored database: err = %v, want ErrNotEmpty", err)
+	}
+}
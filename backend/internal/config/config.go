@@ -0,0 +1,202 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"time"
+)
+
+// Config is everything the binary can be configured with. It is read from an optional
+// YAML file and then from environment variables, which take precedence.
+type Config struct {
+	// Storage is "postgres" or "memory", which keeps everything in the process.
+	Storage  string   `yaml:"storage"`
+	HTTP     HTTP     `yaml:"http"`
+	Postgres Postgres `yaml:"postgres"`
+	Elastic  Elastic  `yaml:"elastic"`
+	Search   Search   `yaml:"search"`
+	Auth     Auth     `yaml:"auth"`
+	Log      Log      `yaml:"log"`
+	CORS     CORS     `yaml:"cors"`
+	Features Features `yaml:"features"`
+	// Admin is created at startup with memory storage, which has no other way to get one.
+	Admin Admin `yaml:"admin"`
+}
+
+type HTTP struct {
+	Port            string        `yaml:"port"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+type Postgres struct {
+	URL            string        `yaml:"url"`
+	MaxConns       int32         `yaml:"max_conns"` // 0 keeps the pgxpool default
+	MinConns       int32         `yaml:"min_conns"`
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+}
+
+type Elastic struct {
+	URL string `yaml:"url"`
+}
+
+type Search struct {
+	// Backend is "elastic" or "postgres"; ignored with memory storage.
+	Backend      string `yaml:"backend"`
+	SynonymsFile string `yaml:"synonyms_file"`
+	// Fallback "postgres" serves searches from Postgres while Elasticsearch is down.
+	Fallback string `yaml:"fallback"`
+}
+
+// Auth signs tokens with the keys in KeysDir, or with HS256 and SecretKey without one.
+type Auth struct {
+	SecretKey    string `yaml:"secret_key"`
+	KeysDir      string `yaml:"keys_dir"`
+	SigningKeyID string `yaml:"signing_key_id"`
+}
+
+type Log struct {
+	// File is appended to in addition to stdout; empty logs to stdout only.
+	File   string `yaml:"file"`
+	Level  string `yaml:"level"`  // debug, info, warn or error
+	Format string `yaml:"format"` // text or json
+}
+
+type CORS struct {
+	// AllowedOrigins empty allows every origin.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+type Features struct {
+	// ReindexOnStart rebuilds the search index from the database at startup.
+	ReindexOnStart bool `yaml:"reindex_on_start"`
+	// MigrateOnStart applies pending database migrations before serving.
+	MigrateOnStart bool `yaml:"migrate_on_start"`
+	// OutboxDispatcher applies catalog changes to the search index; with several
+	// replicas it can run on one of them only.
+	OutboxDispatcher bool `yaml:"outbox_dispatcher"`
+}
+
+type Admin struct {
+	Name     string `yaml:"name"`
+	Password string `yaml:"password"`
+}
+
+// Default is the configuration before the file and the environment are applied.
+func Default() Config {
+	return Config{
+		Storage: "postgres",
+		HTTP: HTTP{
+			Port:            "8080",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     2 * time.Minute,
+			ShutdownTimeout: 20 * time.Second,
+		},
+		Postgres: Postgres{ConnectTimeout: 5 * time.Second},
+		Search:   Search{Backend: "elastic"},
+		Log:      Log{File: "log.txt", Level: "debug", Format: "text"},
+		Features: Features{ReindexOnStart: true, OutboxDispatcher: true},
+	}
+}
+
+// Load reads path, if not empty, then the environment. The server Validates the result;
+// commands that only need some of it, like migrate, check what they use.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read config: %w", err)
+		}
+		if err := yaml.Unmarshal(b, &cfg); err != nil {
+			return nil, fmt.Errorf("parse config %s: %w", path, err)
+		}
+	}
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate reports every invalid value at once, named after its environment variable.
+func (c *Config) Validate() error {
+	errs := []error{c.ValidateStorage()}
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Auth.KeysDir == "" && c.Auth.SecretKey == "" {
+		fail("SECRET_KEY is required when JWT_KEYS_DIR is not set, tokens would be signed with an empty key")
+	}
+	if c.HTTP.Port == "" {
+		fail("PORT is required")
+	}
+	for name, d := range map[string]time.Duration{
+		"HTTP_READ_TIMEOUT":     c.HTTP.ReadTimeout,
+		"HTTP_WRITE_TIMEOUT":    c.HTTP.WriteTimeout,
+		"HTTP_IDLE_TIMEOUT":     c.HTTP.IdleTimeout,
+		"HTTP_SHUTDOWN_TIMEOUT": c.HTTP.ShutdownTimeout,
+	} {
+		if d <= 0 {
+			fail("%s must be positive, got %s", name, d)
+		}
+	}
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		fail("LOG_LEVEL must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+	if c.Log.Format != "text" && c.Log.Format != "json" {
+		fail("LOG_FORMAT must be text or json, got %q", c.Log.Format)
+	}
+	if c.Admin.Name != "" && c.Admin.Password == "" {
+		fail("ADMIN_PASSWORD is required with ADMIN_NAME")
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateStorage checks only what connecting to the database and search backend needs,
+// for commands that do not serve HTTP.
+func (c *Config) ValidateStorage() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	switch c.Storage {
+	case "postgres":
+		if c.Postgres.URL == "" {
+			fail("DB_POSTGRES_URL is required with postgres storage")
+		}
+		switch c.Search.Backend {
+		case "elastic":
+			if c.Elastic.URL == "" {
+				fail("ELASTIC_URL is required with the elastic search backend")
+			}
+		case "postgres":
+		default:
+			fail("SEARCH_BACKEND must be elastic or postgres, got %q", c.Search.Backend)
+		}
+		if c.Search.Fallback != "" && c.Search.Fallback != "postgres" {
+			fail("SEARCH_FALLBACK must be empty or postgres, got %q", c.Search.Fallback)
+		}
+	case "memory":
+	default:
+		fail("STORAGE_BACKEND must be postgres or memory, got %q", c.Storage)
+	}
+
+	if c.Postgres.ConnectTimeout <= 0 {
+		fail("DB_POSTGRES_CONNECT_TIMEOUT must be positive, got %s", c.Postgres.ConnectTimeout)
+	}
+	if c.Postgres.MaxConns < 0 || c.Postgres.MinConns < 0 ||
+		(c.Postgres.MaxConns > 0 && c.Postgres.MinConns > c.Postgres.MaxConns) {
+		fail("DB_POSTGRES_MIN_CONNS (%d) and DB_POSTGRES_MAX_CONNS (%d) must be non-negative and min <= max",
+			c.Postgres.MinConns, c.Postgres.MaxConns)
+	}
+	return errors.Join(errs...)
+}
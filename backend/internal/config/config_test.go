@@ -0,0 +1,83 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFileThenEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+storage: postgres
+http:
+  port: "9000"
+  read_timeout: 3s
+postgres:
+  url: postgres://file
+  max_conns: 20
+search:
+  backend: postgres
+auth:
+  secret_key: from-file
+cors:
+  allowed_origins: [https://igropoisk.example]
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DB_POSTGRES_URL", "postgres://env")
+	t.Setenv("LOG_FORMAT", "json")
+	t.Setenv("REINDEX_ON_START", "false")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Postgres.URL != "postgres://env" {
+		t.Errorf("env did not override the file: %q", cfg.Postgres.URL)
+	}
+	if cfg.HTTP.Port != "9000" || cfg.HTTP.ReadTimeout != 3*time.Second || cfg.Postgres.MaxConns != 20 {
+		t.Errorf("file values not applied: %+v %+v", cfg.HTTP, cfg.Postgres)
+	}
+	if cfg.HTTP.WriteTimeout != Default().HTTP.WriteTimeout {
+		t.Errorf("default lost: write timeout %s", cfg.HTTP.WriteTimeout)
+	}
+	if cfg.Log.Format != "json" || cfg.Features.ReindexOnStart || !cfg.Features.OutboxDispatcher {
+		t.Errorf("env values not applied: %+v %+v", cfg.Log, cfg.Features)
+	}
+	if len(cfg.CORS.AllowedOrigins) != 1 {
+		t.Errorf("allowed origins = %v", cfg.CORS.AllowedOrigins)
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := Default()
+	cfg.Log.Level = "verbose"
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"DB_POSTGRES_URL", "ELASTIC_URL", "SECRET_KEY", "LOG_LEVEL"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %s:\n%s", want, err)
+		}
+	}
+}
+
+func TestApplyEnvRejectsMalformedValues(t *testing.T) {
+	env := map[string]string{"HTTP_READ_TIMEOUT": "10", "OUTBOX_DISPATCHER": "sometimes", "CORS_ALLOWED_ORIGINS": "a, b,"}
+	cfg := Default()
+	err := cfg.applyEnv(func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	})
+	if err == nil || !strings.Contains(err.Error(), "HTTP_READ_TIMEOUT") || !strings.Contains(err.Error(), "OUTBOX_DISPATCHER") {
+		t.Fatalf("applyEnv error = %v", err)
+	}
+	if len(cfg.CORS.AllowedOrigins) != 2 {
+		t.Fatalf("allowed origins = %q", cfg.CORS.AllowedOrigins)
+	}
+}
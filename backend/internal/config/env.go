@@ -0,0 +1,97 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// applyEnv overrides the values whose variables are set, even to an empty string.
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+	str := func(name string, dst *string) {
+		if v, ok := lookup(name); ok {
+			*dst = v
+		}
+	}
+	list := func(name string, dst *[]string) {
+		if v, ok := lookup(name); ok {
+			*dst = nil
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					*dst = append(*dst, item)
+				}
+			}
+		}
+	}
+	boolean := func(name string, dst *bool) {
+		if v, ok := lookup(name); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be a boolean, got %q", name, v))
+				return
+			}
+			*dst = b
+		}
+	}
+	int32s := func(name string, dst *int32) {
+		if v, ok := lookup(name); ok {
+			n, err := strconv.ParseInt(v, 10, 32)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be an integer, got %q", name, v))
+				return
+			}
+			*dst = int32(n)
+		}
+	}
+	duration := func(name string, dst *time.Duration) {
+		if v, ok := lookup(name); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be a duration like 10s, got %q", name, v))
+				return
+			}
+			*dst = d
+		}
+	}
+
+	str("STORAGE_BACKEND", &c.Storage)
+
+	str("PORT", &c.HTTP.Port)
+	duration("HTTP_READ_TIMEOUT", &c.HTTP.ReadTimeout)
+	duration("HTTP_WRITE_TIMEOUT", &c.HTTP.WriteTimeout)
+	duration("HTTP_IDLE_TIMEOUT", &c.HTTP.IdleTimeout)
+	duration("HTTP_SHUTDOWN_TIMEOUT", &c.HTTP.ShutdownTimeout)
+
+	str("DB_POSTGRES_URL", &c.Postgres.URL)
+	int32s("DB_POSTGRES_MAX_CONNS", &c.Postgres.MaxConns)
+	int32s("DB_POSTGRES_MIN_CONNS", &c.Postgres.MinConns)
+	duration("DB_POSTGRES_CONNECT_TIMEOUT", &c.Postgres.ConnectTimeout)
+
+	str("ELASTIC_URL", &c.Elastic.URL)
+
+	str("SEARCH_BACKEND", &c.Search.Backend)
+	str("SEARCH_SYNONYMS_FILE", &c.Search.SynonymsFile)
+	str("SEARCH_FALLBACK", &c.Search.Fallback)
+
+	str("SECRET_KEY", &c.Auth.SecretKey)
+	str("JWT_KEYS_DIR", &c.Auth.KeysDir)
+	str("JWT_SIGNING_KEY_ID", &c.Auth.SigningKeyID)
+
+	str("LOG_FILE", &c.Log.File)
+	str("LOG_LEVEL", &c.Log.Level)
+	str("LOG_FORMAT", &c.Log.Format)
+
+	list("CORS_ALLOWED_ORIGINS", &c.CORS.AllowedOrigins)
+
+	boolean("REINDEX_ON_START", &c.Features.ReindexOnStart)
+	boolean("MIGRATE_ON_START", &c.Features.MigrateOnStart)
+	boolean("OUTBOX_DISPATCHER", &c.Features.OutboxDispatcher)
+
+	str("ADMIN_NAME", &c.Admin.Name)
+	str("ADMIN_PASSWORD", &c.Admin.Password)
+
+	return errors.Join(errs...)
+}
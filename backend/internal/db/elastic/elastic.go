@@ -1,20 +1,16 @@
 package elastic
 
 import (
+	"fmt"
 	"github.com/elastic/go-elasticsearch/v8"
-	"log"
-	"os"
 )
 
-func NewClient() *elasticsearch.Client {
-	url := os.Getenv("ELASTIC_URL")
-
-	cfg := elasticsearch.Config{
+func NewClient(url string) (*elasticsearch.Client, error) {
+	es, err := elasticsearch.NewClient(elasticsearch.Config{
 		Addresses: []string{url},
-	}
-	es, err := elasticsearch.NewClient(cfg)
+	})
 	if err != nil {
-		log.Fatalf("Error creating Elastic client: %s", err.Error())
+		return nil, fmt.Errorf("error creating Elastic client: %w", err)
 	}
-	return es
+	return es, nil
 }
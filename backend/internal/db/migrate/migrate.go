@@ -0,0 +1,316 @@
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+//go:embed queries/create_schema_migrations.sql
+var createSchemaMigrationsSQL string
+
+//go:embed queries/get_applied_migrations.sql
+var getAppliedMigrationsSQL string
+
+//go:embed queries/add_applied_migration.sql
+var addAppliedMigrationSQL string
+
+//go:embed queries/remove_applied_migration.sql
+var removeAppliedMigrationSQL string
+
+// lockID is the pg_advisory_lock key held while migrating, so concurrent
+// runners (e.g. several replicas starting at once) apply each migration once.
+const lockID = 4_207_733_071
+
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string // empty when the migration cannot be reverted
+	Checksum string // of Up, detects files edited after they were applied
+}
+
+// Status is a migration as known to the binary, the database or both.
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+	// Modified is set when the applied file no longer matches the embedded one.
+	Modified bool `json:"modified,omitempty"`
+	// Unknown is set for versions applied by a newer binary.
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Load reads "<version>_<name>.up.sql" and optional matching ".down.sql" files from fsys.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		m := fileName.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("%s: invalid version", entry.Name())
+		}
+		b, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != m[2] {
+			return nil, fmt.Errorf("version %d is used by both %q and %q", version, migration.Name, m[2])
+		}
+		if m[3] == "up" {
+			migration.Up = string(b)
+			sum := sha256.Sum256(b)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(b)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+	return migrations, nil
+}
+
+type applied struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+}
+
+func New(pool *pgxpool.Pool, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("load migrations: %w", err)
+	}
+	return &Migrator{pool: pool, migrations: migrations}, nil
+}
+
+// locked runs fn on a single connection holding the advisory lock.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgx.Conn, done []applied) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("lock migrations: %w", err)
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockID)
+
+	if _, err := conn.Exec(ctx, createSchemaMigrationsSQL); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	rows, err := conn.Query(ctx, getAppliedMigrationsSQL)
+	if err != nil {
+		return fmt.Errorf("get applied migrations: %w", err)
+	}
+	done, err := pgx.CollectRows(rows, pgx.RowToStructByPos[applied])
+	if err != nil {
+		return fmt.Errorf("get applied migrations: %w", err)
+	}
+	return fn(conn.Conn(), done)
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	i, ok := slices.BinarySearchFunc(m.migrations, version, func(m Migration, v int64) int { return cmp.Compare(m.Version, v) })
+	if !ok {
+		return nil
+	}
+	return &m.migrations[i]
+}
+
+// verify refuses to build on a history that differs from the embedded files.
+func (m *Migrator) verify(done []applied) error {
+	var errs []error
+	for _, a := range done {
+		migration := m.find(a.Version)
+		switch {
+		case migration == nil:
+			errs = append(errs, fmt.Errorf("migration %d_%s is applied but unknown to this binary", a.Version, a.Name))
+		case migration.Checksum != a.Checksum:
+			errs = append(errs, fmt.Errorf("migration %d_%s was modified after it was applied", a.Version, a.Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Migrator) run(ctx context.Context, conn *pgx.Conn, sql string, record func(tx pgx.Tx) error) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Up applies every pending migration in order, each in its own transaction,
+// and returns the ones it applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var ran []Migration
+	err := m.locked(ctx, func(conn *pgx.Conn, done []applied) error {
+		if err := m.verify(done); err != nil {
+			return err
+		}
+		isApplied := make(map[int64]bool, len(done))
+		for _, a := range done {
+			isApplied[a.Version] = true
+		}
+		for _, migration := range m.migrations {
+			if isApplied[migration.Version] {
+				continue
+			}
+			err := m.run(ctx, conn, migration.Up, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, addAppliedMigrationSQL, migration.Version, migration.Name, migration.Checksum)
+				return err
+			})
+			var pgErr *pgconn.PgError
+			if len(done) == 0 && errors.As(err, &pgErr) && pgErr.Code == "42P07" {
+				return fmt.Errorf("migration %d_%s: %w; the schema predates tracked migrations, "+
+					"record the ones it already has with: migrate baseline <version>", migration.Version, migration.Name, err)
+			}
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			ran = append(ran, migration)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Baseline records the migrations up to version as applied without running them, for
+// databases created from the schema files before migrations were tracked. It refuses
+// to touch a database that already tracks migrations.
+func (m *Migrator) Baseline(ctx context.Context, version int64) ([]Migration, error) {
+	if m.find(version) == nil {
+		return nil, fmt.Errorf("unknown migration version %d", version)
+	}
+	var marked []Migration
+	err := m.locked(ctx, func(conn *pgx.Conn, done []applied) error {
+		if len(done) > 0 {
+			return fmt.Errorf("the database already tracks %d migrations, baseline only applies to untracked ones", len(done))
+		}
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+		for _, migration := range m.migrations {
+			if migration.Version > version {
+				break
+			}
+			if _, err := tx.Exec(ctx, addAppliedMigrationSQL, migration.Version, migration.Name, migration.Checksum); err != nil {
+				return fmt.Errorf("record %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			marked = append(marked, migration)
+		}
+		return tx.Commit(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return marked, nil
+}
+
+// Down reverts the last steps applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var ran []Migration
+	err := m.locked(ctx, func(conn *pgx.Conn, done []applied) error {
+		if err := m.verify(done); err != nil {
+			return err
+		}
+		for i := len(done) - 1; i >= 0 && len(ran) < steps; i-- {
+			migration := m.find(done[i].Version)
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s cannot be reverted, it has no down file", migration.Version, migration.Name)
+			}
+			err := m.run(ctx, conn, migration.Down, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, removeAppliedMigrationSQL, migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("revert %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			ran = append(ran, *migration)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Status lists every migration, embedded or applied, by version.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.locked(ctx, func(conn *pgx.Conn, done []applied) error {
+		byVersion := make(map[int64]*Status, len(m.migrations))
+		for _, migration := range m.migrations {
+			statuses = append(statuses, Status{Version: migration.Version, Name: migration.Name})
+		}
+		for i := range statuses {
+			byVersion[statuses[i].Version] = &statuses[i]
+		}
+		var unknown []Status
+		for _, a := range done {
+			appliedAt := a.AppliedAt
+			s, ok := byVersion[a.Version]
+			if !ok {
+				unknown = append(unknown, Status{Version: a.Version, Name: a.Name, AppliedAt: &appliedAt, Unknown: true})
+				continue
+			}
+			s.AppliedAt = &appliedAt
+			s.Modified = m.find(a.Version).Checksum != a.Checksum
+		}
+		statuses = append(statuses, unknown...)
+		return nil
+	})
+	slices.SortFunc(statuses, func(a, b Status) int { return cmp.Compare(a.Version, b.Version) })
+	return statuses, err
+}
@@ -0,0 +1,155 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"igropoisk_backend/migrations"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestLoadOrdersAndPairsFiles(t *testing.T) {
+	migrations, err := Load(fstest.MapFS{
+		"0010_add_index.up.sql":      {Data: []byte("CREATE INDEX i ON t (c);")},
+		"0002_create_table.up.sql":   {Data: []byte("CREATE TABLE t (c INT);")},
+		"0002_create_table.down.sql": {Data: []byte("DROP TABLE t;")},
+		"README.md":                  {Data: []byte("not a migration")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 2 || migrations[1].Version != 10 {
+		t.Fatalf("migrations = %+v", migrations)
+	}
+	if migrations[0].Down != "DROP TABLE t;" || migrations[1].Down != "" {
+		t.Fatalf("down files not paired: %+v", migrations)
+	}
+	if migrations[0].Checksum == "" || migrations[0].Checksum == migrations[1].Checksum {
+		t.Fatalf("checksums = %q, %q", migrations[0].Checksum, migrations[1].Checksum)
+	}
+}
+
+func TestLoadRejectsInconsistentFiles(t *testing.T) {
+	for name, fsys := range map[string]fstest.MapFS{
+		"duplicate version": {
+			"0001_a.up.sql": {Data: []byte("SELECT 1;")},
+			"0001_b.up.sql": {Data: []byte("SELECT 2;")},
+		},
+		"down without up": {
+			"0001_a.down.sql": {Data: []byte("SELECT 1;")},
+		},
+	} {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	all, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range all {
+		if m.Version != int64(i+1) {
+			t.Errorf("version %d follows %d, versions must be consecutive", m.Version, i)
+		}
+		if strings.TrimSpace(m.Down) == "" {
+			t.Errorf("migration %d_%s has no down file", m.Version, m.Name)
+		}
+	}
+}
+
+// testPool connects to TEST_DB_POSTGRES_URL in a fresh schema, which is dropped afterwards.
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_DB_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_DB_POSTGRES_URL is not set")
+	}
+	ctx := context.Background()
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	admin, err := pgx.Connect(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close(ctx)
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if conn, err := pgx.Connect(ctx, url); err == nil {
+			conn.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE")
+			conn.Close(ctx)
+		}
+	})
+
+	cfg, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema + ",public"
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestBaselineExistingSchema(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	legacy, err := os.ReadFile("testdata/legacy_schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Exec(ctx, string(legacy)); err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := New(pool, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := migrator.Up(ctx); err == nil || !strings.Contains(err.Error(), "migrate baseline") {
+		t.Fatalf("Up on an untracked schema: %v, want a hint to baseline", err)
+	}
+	if _, err := migrator.Baseline(ctx, 99); err == nil {
+		t.Fatal("baselined to an unknown version")
+	}
+	marked, err := migrator.Baseline(ctx, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(marked) != 5 {
+		t.Fatalf("marked %d migrations, want 5", len(marked))
+	}
+	if _, err := migrator.Baseline(ctx, 5); err == nil {
+		t.Fatal("baselined a database that already tracks migrations")
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var role string
+	if err := pool.QueryRow(ctx, "SELECT role FROM users WHERE name = 'alice'").Scan(&role); err != nil {
+		t.Fatal(err)
+	}
+	if role != "user" {
+		t.Fatalf("role = %q, want user", role)
+	}
+	var genres []string
+	rows, err := pool.Query(ctx, "SELECT ge.name FROM game_genres gg JOIN genres ge ON ge.id = gg.genre_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if genres, err = pgx.CollectRows(rows, pgx.RowTo[string]); err != nil || len(genres) != 1 || genres[0] != "RPG" {
+		t.Fatalf("game genres = %v, %v; want the legacy genre_id carried over", genres, err)
+	}
+}
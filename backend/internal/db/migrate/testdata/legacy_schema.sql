@@ -0,0 +1,37 @@
+-- the schema as the old loose files and the code before user roles left it
+CREATE TABLE genres (
+    id   SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE games (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    avg_rating NUMERIC(4,2) DEFAULT NULL,
+    reviews_count INT NOT NULL DEFAULT 0,
+    description TEXT,
+    image_url TEXT NOT NULL,
+    genre_id INT NOT NULL REFERENCES genres(id)
+);
+
+CREATE TABLE users (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(32) NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE TABLE reviews (
+    id SERIAL PRIMARY KEY,
+    game_id INT NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rating INT NOT NULL,
+    description TEXT,
+    CHECK (rating >= 0 AND rating <= 10),
+    UNIQUE (game_id, user_id)
+);
+
+INSERT INTO genres (name) VALUES ('RPG');
+INSERT INTO games (name, description, image_url, genre_id) VALUES ('Gothic', 'Old school RPG', 'img.png', 1);
+INSERT INTO users (name, password_hash) VALUES ('alice', 'hash');
@@ -2,30 +2,45 @@ package postgres
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/jackc/pgx/v5/stdlib"
-	"log"
-	"os"
 	"time"
 )
 
-func GetPool() *pgxpool.Pool {
-	dsn := os.Getenv("DB_POSTGRES_URL")
-	if dsn == "" {
-		log.Fatal("DB_POSTGRES_URL is not set")
+type Options struct {
+	URL            string
+	MaxConns       int32 // 0 keeps the pgxpool default
+	MinConns       int32
+	ConnectTimeout time.Duration
+}
+
+func NewPool(ctx context.Context, opts Options) (*pgxpool.Pool, error) {
+	if opts.URL == "" {
+		return nil, errors.New("DB_POSTGRES_URL is not set")
+	}
+	poolCfg, err := pgxpool.ParseConfig(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_POSTGRES_URL: %w", err)
+	}
+	if opts.MaxConns > 0 {
+		poolCfg.MaxConns = opts.MaxConns
 	}
+	poolCfg.MinConns = opts.MinConns
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, opts.ConnectTimeout)
 	defer cancel()
 
-	pool, err := pgxpool.New(ctx, dsn)
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
 	if err != nil {
-		log.Fatalf("unable to create connection pool: %v", err)
+		return nil, fmt.Errorf("unable to create connection pool: %w", err)
 	}
 
 	if err := pool.Ping(ctx); err != nil {
-		log.Fatalf("unable to ping database: %v", err)
+		pool.Close()
+		return nil, fmt.Errorf("unable to ping database: %w", err)
 	}
 
-	return pool
+	return pool, nil
 }
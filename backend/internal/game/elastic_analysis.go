@@ -0,0 +1,122 @@
+package game
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//go:embed mappings/synonyms.txt
+var defaultSynonyms string
+
+// cyrillicToLatin folds Russian spelling into the Latin alphabet, so "майнкрафт" and
+// "mainkraft" produce the same tokens in the name.translit field.
+var cyrillicToLatin = map[string]string{
+	"а": "a", "б": "b", "в": "v", "г": "g", "д": "d", "е": "e", "ё": "e", "ж": "zh",
+	"з": "z", "и": "i", "й": "i", "к": "k", "л": "l", "м": "m", "н": "n", "о": "o",
+	"п": "p", "р": "r", "с": "s", "т": "t", "у": "u", "ф": "f", "х": "h", "ц": "ts",
+	"ч": "ch", "ш": "sh", "щ": "sch", "ъ": "", "ы": "i", "ь": "", "э": "e", "ю": "iu",
+	"я": "ia",
+}
+
+// latinFolding then merges Latin letters that Russian spelling cannot tell apart
+// ("minecraft" becomes "minekraft", two edits away from "mainkraft", which AUTO
+// fuzziness still allows for terms longer than five characters).
+var latinFolding = map[string]string{
+	"ck": "k", "c": "k", "ch": "ch", "q": "k", "w": "v", "y": "i", "ph": "f", "x": "ks",
+}
+
+// charMappings renders a table as mapping char filter rules for both letter cases;
+// char filters run before the lowercase token filter.
+func charMappings(table map[string]string) []string {
+	rules := make([]string, 0, len(table)*2)
+	for from, to := range table {
+		rules = append(rules, from+" => "+to)
+		upper := []rune(from)
+		upper[0] = unicode.ToUpper(upper[0])
+		rules = append(rules, string(upper)+" => "+to)
+	}
+	sort.Strings(rules)
+	return rules
+}
+
+// LoadSynonyms reads a Solr-format synonym file, one rule per line; blank lines and # comments are skipped.
+func LoadSynonyms(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseSynonyms(bufio.NewScanner(f))
+}
+
+func parseSynonyms(scanner *bufio.Scanner) ([]string, error) {
+	rules := []string{}
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rules = append(rules, line)
+	}
+	return rules, scanner.Err()
+}
+
+// analysisSettings defines the analyzers referenced by mappings/games.json:
+// ru_en stems Russian and English at index time, ru_en_search adds synonyms at query time,
+// and translit folds both alphabets into one for typo-tolerant matching across them.
+func analysisSettings(synonyms []string) map[string]any {
+	searchFilters := []string{"lowercase"}
+	filters := map[string]any{
+		"russian_stemmer": map[string]any{"type": "stemmer", "language": "russian"},
+		"english_stemmer": map[string]any{"type": "stemmer", "language": "english"},
+	}
+	if len(synonyms) > 0 {
+		filters["game_synonyms"] = map[string]any{"type": "synonym_graph", "synonyms": synonyms, "lenient": true}
+		searchFilters = append(searchFilters, "game_synonyms")
+	}
+	searchFilters = append(searchFilters, "russian_stemmer", "english_stemmer")
+
+	return map[string]any{
+		"char_filter": map[string]any{
+			"cyrillic_to_latin": map[string]any{"type": "mapping", "mappings": charMappings(cyrillicToLatin)},
+			"latin_folding":     map[string]any{"type": "mapping", "mappings": charMappings(latinFolding)},
+		},
+		"filter": filters,
+		"analyzer": map[string]any{
+			"ru_en": map[string]any{
+				"tokenizer": "standard",
+				"filter":    []string{"lowercase", "russian_stemmer", "english_stemmer"},
+			},
+			"ru_en_search": map[string]any{
+				"tokenizer": "standard",
+				"filter":    searchFilters,
+			},
+			"translit": map[string]any{
+				"char_filter": []string{"cyrillic_to_latin", "latin_folding"},
+				"tokenizer":   "standard",
+				"filter":      []string{"lowercase"},
+			},
+		},
+	}
+}
+
+// indexDefinition merges the generated analysis settings into the static mapping.
+func indexDefinition(synonyms []string) (string, error) {
+	var def map[string]any
+	if err := json.Unmarshal([]byte(gamesIndexMapping), &def); err != nil {
+		return "", err
+	}
+	settings, _ := def["settings"].(map[string]any)
+	if settings == nil {
+		settings = map[string]any{}
+		def["settings"] = settings
+	}
+	settings["analysis"] = analysisSettings(synonyms)
+	b, err := json.Marshal(def)
+	return string(b), err
+}
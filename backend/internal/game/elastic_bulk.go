@@ -0,0 +1,196 @@
+package game
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"igropoisk_backend/internal/logger"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type BulkOptions struct {
+	BatchSize int // documents per _bulk request
+	Workers   int // concurrent _bulk requests
+}
+
+const (
+	defaultBulkBatchSize = 1000
+	defaultBulkWorkers   = 4
+	maxReportedErrors    = 100
+	progressInterval     = 5 * time.Second
+)
+
+func (o BulkOptions) withDefaults() BulkOptions {
+	if o.BatchSize <= 0 {
+		o.BatchSize = defaultBulkBatchSize
+	}
+	if o.Workers <= 0 {
+		o.Workers = defaultBulkWorkers
+	}
+	return o
+}
+
+type BulkItemError struct {
+	GameID int    `json:"game_id"`
+	Status int    `json:"status"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		ID     string `json:"_id"`
+		Status int    `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// bulkIndex writes games into index with a single _bulk request and returns the items it rejected.
+func (r *ElasticRepository) bulkIndex(ctx context.Context, index string, games []Game) ([]BulkItemError, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i := range games {
+		meta := map[string]any{"index": map[string]any{"_index": index, "_id": strconv.Itoa(games[i].ID)}}
+		if err := enc.Encode(meta); err != nil {
+			return nil, err
+		}
+		if err := enc.Encode(newGameDocument(&games[i])); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := r.es.Bulk(&buf, r.es.Bulk.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, readError(res)
+	}
+	var bulkRes bulkResponse
+	if err := json.NewDecoder(res.Body).Decode(&bulkRes); err != nil {
+		return nil, err
+	}
+	if !bulkRes.Errors {
+		return nil, nil
+	}
+
+	var failed []BulkItemError
+	for _, item := range bulkRes.Items {
+		for _, result := range item {
+			if result.Error == nil {
+				continue
+			}
+			id, _ := strconv.Atoi(result.ID)
+			failed = append(failed, BulkItemError{
+				GameID: id,
+				Status: result.Status,
+				Type:   result.Error.Type,
+				Reason: result.Error.Reason,
+			})
+		}
+	}
+	return failed, nil
+}
+
+// load streams every game from repo into index: one producer fills batches
+// and a bounded pool of workers sends them to _bulk.
+func (r *ElasticRepository) load(ctx context.Context, index string, repo Repository) (*ReindexResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := &ReindexResult{Index: index}
+	batches := make(chan []Game, r.bulk.Workers)
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+
+	start := time.Now()
+	lastProgress := start
+	for i := 0; i < r.bulk.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				failed, err := r.bulkIndex(ctx, index, batch)
+				if err != nil {
+					fail(fmt.Errorf("bulk request for games %d..%d: %w", batch[0].ID, batch[len(batch)-1].ID, err))
+					continue
+				}
+				mu.Lock()
+				result.Documents += len(batch) - len(failed)
+				result.Failed += len(failed)
+				for _, item := range failed {
+					if len(result.Errors) < maxReportedErrors {
+						result.Errors = append(result.Errors, item)
+					}
+				}
+				if time.Since(lastProgress) >= progressInterval {
+					lastProgress = time.Now()
+					logger.Logger.Info("Reindex progress",
+						"index", index,
+						"documents", result.Documents,
+						"failed", result.Failed,
+						"docs_per_second", int(float64(result.Documents)/time.Since(start).Seconds()))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	batch := make([]Game, 0, r.bulk.BatchSize)
+	err := repo.StreamGames(ctx, func(game Game) error {
+		batch = append(batch, game)
+		if len(batch) < r.bulk.BatchSize {
+			return nil
+		}
+		select {
+		case batches <- batch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		batch = make([]Game, 0, r.bulk.BatchSize)
+		return nil
+	})
+	if err == nil && len(batch) > 0 {
+		select {
+		case batches <- batch:
+		case <-ctx.Done():
+		}
+	}
+	close(batches)
+	wg.Wait()
+
+	if firstErr != nil {
+		return result, firstErr
+	}
+	if err != nil {
+		return result, err
+	}
+	for _, item := range result.Errors {
+		logger.Logger.Warn("Game failed to index",
+			"index", index,
+			"game_id", item.GameID,
+			"type", item.Type,
+			"reason", item.Reason)
+	}
+	return result, nil
+}
@@ -0,0 +1,241 @@
+package game
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"igropoisk_backend/internal/logger"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+//go:embed mappings/games.json
+var gamesIndexMapping string
+
+// gamesAlias is what every read and write goes through; it points at one games_v{N} index.
+const (
+	gamesAlias       = "games"
+	gamesIndexPrefix = gamesAlias + "_v"
+)
+
+// ReindexResult lists the documents Elasticsearch rejected in Errors; the new index goes
+// live without them, and their next write or reindex adds them again.
+type ReindexResult struct {
+	Index     string          `json:"index"`
+	Documents int             `json:"documents"`
+	Failed    int             `json:"failed"`
+	Errors    []BulkItemError `json:"errors,omitempty"`
+	Replayed  int             `json:"replayed"` // games written during the load and indexed again
+	Removed   []string        `json:"removed_indices"`
+	Took      string          `json:"took"`
+}
+
+func readError(res *esapi.Response) error {
+	body, _ := io.ReadAll(res.Body)
+	return fmt.Errorf("elastic: %s: %s", res.Status(), body)
+}
+
+// gameIndices returns the existing versioned indices, oldest first.
+func (r *ElasticRepository) gameIndices(ctx context.Context) ([]string, error) {
+	res, err := r.es.Indices.Get([]string{gamesIndexPrefix + "*"},
+		r.es.Indices.Get.WithContext(ctx),
+		r.es.Indices.Get.WithAllowNoIndices(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, readError(res)
+	}
+	var indices map[string]json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(indices))
+	for name := range indices {
+		if indexVersion(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return indexVersion(names[i]) < indexVersion(names[j]) })
+	return names, nil
+}
+
+func indexVersion(name string) int {
+	v, err := strconv.Atoi(strings.TrimPrefix(name, gamesIndexPrefix))
+	if err != nil || !strings.HasPrefix(name, gamesIndexPrefix) {
+		return 0
+	}
+	return v
+}
+
+func (r *ElasticRepository) createIndex(ctx context.Context, name string) error {
+	definition, err := indexDefinition(r.synonyms)
+	if err != nil {
+		return err
+	}
+	res, err := r.es.Indices.Create(name,
+		r.es.Indices.Create.WithContext(ctx),
+		r.es.Indices.Create.WithBody(strings.NewReader(definition)),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return readError(res)
+	}
+	return nil
+}
+
+// legacyIndexExists reports whether "games" is still a concrete index from before aliases were used.
+func (r *ElasticRepository) legacyIndexExists(ctx context.Context) (bool, error) {
+	res, err := r.es.Indices.Get([]string{gamesAlias}, r.es.Indices.Get.WithContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == 404 {
+		return false, nil
+	}
+	if res.IsError() {
+		return false, readError(res)
+	}
+	var indices map[string]json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		return false, err
+	}
+	_, ok := indices[gamesAlias]
+	return ok, nil
+}
+
+// swapAlias points the alias at index in a single atomic request.
+func (r *ElasticRepository) swapAlias(ctx context.Context, index string, previous []string, dropLegacy bool) error {
+	actions := []map[string]any{}
+	if dropLegacy {
+		actions = append(actions, map[string]any{"remove_index": map[string]any{"index": gamesAlias}})
+	}
+	for _, old := range previous {
+		actions = append(actions, map[string]any{"remove": map[string]any{"index": old, "alias": gamesAlias}})
+	}
+	actions = append(actions, map[string]any{"add": map[string]any{"index": index, "alias": gamesAlias, "is_write_index": true}})
+	body, err := json.Marshal(map[string]any{"actions": actions})
+	if err != nil {
+		return err
+	}
+
+	res, err := r.es.Indices.UpdateAliases(bytes.NewReader(body), r.es.Indices.UpdateAliases.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return readError(res)
+	}
+	return nil
+}
+
+func (r *ElasticRepository) deleteIndices(ctx context.Context, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	res, err := r.es.Indices.Delete(names,
+		r.es.Indices.Delete.WithContext(ctx),
+		r.es.Indices.Delete.WithIgnoreUnavailable(true),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return readError(res)
+	}
+	return nil
+}
+
+// reindexReplayMargin widens the replay window against clock skew between the app and
+// the database; replaying a game twice is harmless.
+const reindexReplayMargin = time.Minute
+
+// replay indexes again, through the alias, every game the outbox saw change since.
+// The bulk load may have written a stale copy of them, and other replicas only
+// wrote them to the previous index.
+func (r *ElasticRepository) replay(ctx context.Context, repo Repository, since time.Time) (int, error) {
+	ids, err := r.outbox.GamesChangedSince(ctx, since)
+	if err != nil {
+		return 0, err
+	}
+	for i, id := range ids {
+		if err := syncGame(ctx, repo, r, id); err != nil {
+			return i, fmt.Errorf("game %d: %w", id, err)
+		}
+	}
+	return len(ids), nil
+}
+
+// Reindex builds a fresh games_v{N} index from repo and swaps the alias over to it,
+// so searches keep hitting the previous index until the new one is complete.
+func (r *ElasticRepository) Reindex(ctx context.Context, repo Repository) (*ReindexResult, error) {
+	start := time.Now()
+	previous, err := r.gameIndices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list indices: %w", err)
+	}
+	version := 1
+	if len(previous) > 0 {
+		version = indexVersion(previous[len(previous)-1]) + 1
+	}
+	index := gamesIndexPrefix + strconv.Itoa(version)
+
+	if err := r.createIndex(ctx, index); err != nil {
+		return nil, fmt.Errorf("create index %s: %w", index, err)
+	}
+	// writes made while loading must land in the new index too; the bulk load may still
+	// overwrite them with rows it read earlier, which the replay below repairs
+	r.setBuilding(index)
+	defer r.setBuilding("")
+
+	result, err := r.load(ctx, index, repo)
+	if err != nil {
+		r.deleteIndices(ctx, []string{index})
+		return result, fmt.Errorf("load index %s: %w", index, err)
+	}
+
+	legacy, err := r.legacyIndexExists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("check legacy index: %w", err)
+	}
+	if err := r.swapAlias(ctx, index, previous, legacy); err != nil {
+		r.deleteIndices(ctx, []string{index})
+		return nil, fmt.Errorf("swap alias to %s: %w", index, err)
+	}
+	r.setBuilding("")
+	if r.outbox != nil {
+		if result.Replayed, err = r.replay(ctx, repo, start.Add(-reindexReplayMargin)); err != nil {
+			return result, fmt.Errorf("replay changes into %s: %w", index, err)
+		}
+	}
+
+	if err := r.deleteIndices(ctx, previous); err != nil {
+		logger.Logger.Warn("Failed to remove old game indices",
+			"indices", previous,
+			"error", err)
+	} else {
+		result.Removed = previous
+	}
+	result.Took = time.Since(start).String()
+	logger.Logger.Info("Search index rebuilt",
+		"index", index,
+		"documents", result.Documents,
+		"replayed", result.Replayed,
+		"took", result.Took)
+	return result, nil
+}
@@ -1,13 +1,18 @@
 package game
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"igropoisk_backend/internal/logger"
+	"net/http"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/elastic/go-elasticsearch/v8"
 )
@@ -15,108 +20,479 @@ import (
 type SearchRepository interface {
 	IndexGame(ctx context.Context, game *Game) error
 	DeleteGame(ctx context.Context, id int) error
-	SearchGames(ctx context.Context, query string) ([]Game, error)
-	SyncWith(ctx context.Context, repo Repository) error
+	SearchGames(ctx context.Context, req SearchRequest) (*SearchResult, error)
+	SuggestGames(ctx context.Context, prefix string, size int) ([]Suggestion, error)
+	SimilarGames(ctx context.Context, id int, size int) ([]Game, error)
+	Reindex(ctx context.Context, repo Repository) (*ReindexResult, error)
+	Ping(ctx context.Context) error
+}
+
+// ElasticOptions tune an ElasticRepository; zero values fall back to defaults.
+type ElasticOptions struct {
+	Bulk BulkOptions
+	// Synonyms in Solr format, e.g. "ведьмак, witcher"; nil uses the built-in list.
+	// Changes apply to the next index built by Reindex.
+	Synonyms []string
+	// Outbox lets Reindex replay the writes made while it loaded the new index,
+	// including those of other replicas; nil skips the replay.
+	Outbox OutboxRepository
 }
 
 type ElasticRepository struct {
-	es *elasticsearch.Client
+	es       *elasticsearch.Client
+	bulk     BulkOptions
+	synonyms []string
+	outbox   OutboxRepository
+
+	mu       sync.RWMutex
+	building string // index being loaded by Reindex, if any
 }
 
-func NewElasticRepository(es *elasticsearch.Client) SearchRepository {
-	return &ElasticRepository{es: es}
+func NewElasticRepository(es *elasticsearch.Client, opts ElasticOptions) SearchRepository {
+	synonyms := opts.Synonyms
+	if synonyms == nil {
+		synonyms, _ = parseSynonyms(bufio.NewScanner(strings.NewReader(defaultSynonyms)))
+	}
+	return &ElasticRepository{es: es, bulk: opts.Bulk.withDefaults(), synonyms: synonyms, outbox: opts.Outbox}
+}
+
+func (r *ElasticRepository) Ping(ctx context.Context) error {
+	res, err := r.es.Ping(r.es.Ping.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return readError(res)
+	}
+	return nil
+}
+
+func (r *ElasticRepository) setBuilding(index string) {
+	r.mu.Lock()
+	r.building = index
+	r.mu.Unlock()
+}
+
+// writeIndices is the alias plus the index a running Reindex is loading.
+func (r *ElasticRepository) writeIndices() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.building == "" {
+		return []string{gamesAlias}
+	}
+	return []string{gamesAlias, r.building}
 }
 
 func (r *ElasticRepository) IndexGame(ctx context.Context, game *Game) error {
-	body, _ := json.Marshal(game)
+	for _, index := range r.writeIndices() {
+		if err := r.indexGame(ctx, index, game); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gameDocument is what gets indexed: the game plus fields only search uses.
+type gameDocument struct {
+	*Game
+	NameSuggest completionInput `json:"name_suggest"`
+	// ReviewsText is left out of _source, similar games read its term vectors
+	ReviewsText string `json:"reviews_text,omitempty"`
+}
+
+type completionInput struct {
+	Input  []string `json:"input"`
+	Weight int      `json:"weight"`
+}
+
+const maxSuggestInputs = 5
+
+// newGameDocument also makes "witcher" complete "The Witcher 3" by indexing every word suffix of the name.
+func newGameDocument(game *Game) gameDocument {
+	words := strings.Fields(game.Name)
+	inputs := make([]string, 0, maxSuggestInputs)
+	for i := 0; i < len(words) && i < maxSuggestInputs; i++ {
+		inputs = append(inputs, strings.Join(words[i:], " "))
+	}
+	// popular games first
+	return gameDocument{
+		Game:        game,
+		NameSuggest: completionInput{Input: inputs, Weight: game.ReviewsCount},
+		ReviewsText: game.ReviewsText,
+	}
+}
+
+func (r *ElasticRepository) indexGame(ctx context.Context, index string, game *Game) error {
+	body, _ := json.Marshal(newGameDocument(game))
 	res, err := r.es.Index(
-		"games",
+		index,
 		bytes.NewReader(body),
-		//r.es.Index.WithDocumentID(fmt.Sprint(game.ID)),
+		r.es.Index.WithContext(ctx),
+		r.es.Index.WithDocumentID(fmt.Sprint(game.ID)),
 	)
+	if err != nil {
+		return err
+	}
 	defer res.Body.Close()
-
-	var indexRes map[string]interface{}
-	json.NewDecoder(res.Body).Decode(&indexRes)
-	fmt.Println(indexRes)
-	logger.Logger.Info("Game indexed",
-		"game_json", string(body))
-	return err
+	if res.IsError() {
+		return errors.New(res.String())
+	}
+	logger.Logger.Debug("Game indexed",
+		"index", index,
+		"game_id", game.ID)
+	return nil
 }
 
 func (r *ElasticRepository) DeleteGame(ctx context.Context, id int) error {
-	_, err := r.es.Delete("games", fmt.Sprint(id))
-	return err
+	for _, index := range r.writeIndices() {
+		if err := r.deleteGame(ctx, index, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ElasticRepository) deleteGame(ctx context.Context, index string, id int) error {
+	res, err := r.es.Delete(index, fmt.Sprint(id), r.es.Delete.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	// already gone is fine, deletes are retried
+	if res.IsError() && res.StatusCode != http.StatusNotFound {
+		return errors.New(res.String())
+	}
+	return nil
+}
+
+// searchSorts maps catalog sort orders onto indexed fields.
+var searchSorts = map[SortOrder]SortField{
+	SortByName:    {Field: "name.keyword", Order: "asc"},
+	SortByRating:  {Field: "avg_rating", Order: "desc", Missing: "_last"},
+	SortByReviews: {Field: "reviews_count", Order: "desc"},
+	SortByNewest:  {Field: "id", Order: "desc"},
+}
+
+const (
+	genreFacetSize = 50
+	tagFacetSize   = 50
+)
+
+func ratingRange(from, to float64) AggregationRange {
+	r := AggregationRange{Key: fmt.Sprintf("%g-%g", from, to), From: &from}
+	if to < 10 {
+		r.To = &to
+	}
+	return r
+}
+
+var ratingFacetRanges = []AggregationRange{
+	ratingRange(0, 2), ratingRange(2, 4), ratingRange(4, 6), ratingRange(6, 8), ratingRange(8, 10),
+}
+
+func buildSearchBody(req SearchRequest) SearchBody {
+	var filters []Query
+	if req.MinReviews > 0 {
+		filters = append(filters, RangeQuery{Field: "reviews_count", Gte: req.MinReviews})
+	}
+	// every tag must match
+	for _, t := range req.Tags {
+		filters = append(filters, TermQuery{Field: "tags.name", Value: t})
+	}
+
+	body := SearchBody{
+		Query: BoolQuery{
+			Must: []Query{MultiMatchQuery{
+				Query:     req.Query,
+				Fields:    []string{"name^3", "name.translit^2", "description"},
+				Fuzziness: "AUTO",
+			}},
+			Filter: filters,
+		},
+		From: req.From,
+		Size: req.Size,
+	}
+
+	// the genre and rating filters are post filters, so each facet can skip its own
+	var genreFilter, ratingFilter Query
+	if len(req.Genres) > 0 {
+		genres := make([]any, len(req.Genres))
+		for i, g := range req.Genres {
+			genres[i] = g
+		}
+		genreFilter = TermsQuery{Field: "genres.name", Values: genres}
+	}
+	if req.MinRating != nil || req.MaxRating != nil {
+		var rng RangeQuery
+		rng.Field = "avg_rating"
+		if req.MinRating != nil {
+			rng.Gte = *req.MinRating
+		}
+		if req.MaxRating != nil {
+			rng.Lte = *req.MaxRating
+		}
+		ratingFilter = rng
+	}
+	body.PostFilter = allOf(genreFilter, ratingFilter)
+	body.Aggs = map[string]Aggregation{
+		"genres":  facetAggregation("genres", TermsAggregation{Field: "genres.name", Size: genreFacetSize}, ratingFilter),
+		"tags":    facetAggregation("tags", TermsAggregation{Field: "tags.name", Size: tagFacetSize}, genreFilter, ratingFilter),
+		"ratings": facetAggregation("ratings", RangeAggregation{Field: "avg_rating", Ranges: ratingFacetRanges}, genreFilter),
+	}
+
+	body.Highlight = &Highlight{
+		PreTags:  []string{"<em>"},
+		PostTags: []string{"</em>"},
+		// names and descriptions are user input, clients render the fragments as HTML
+		Encoder: "html",
+		Fields: map[string]HighlightField{
+			"name":          {NumberOfFragments: 0},
+			"name.translit": {NumberOfFragments: 0},
+			"description":   {FragmentSize: 150, NumberOfFragments: 3},
+		},
+	}
+
+	if sort, ok := searchSorts[req.Sort]; ok {
+		body.Sort = []SortField{sort, {Field: "id", Order: "asc"}}
+	}
+	return body
+}
+
+// allOf combines the non-nil filters; nil when there are none.
+func allOf(filters ...Query) Query {
+	var set []Query
+	for _, f := range filters {
+		if f != nil {
+			set = append(set, f)
+		}
+	}
+	switch len(set) {
+	case 0:
+		return nil
+	case 1:
+		return set[0]
+	}
+	return BoolQuery{Filter: set}
+}
+
+// facetAggregation narrows agg to the post filters of the other facets.
+func facetAggregation(name string, agg Aggregation, filters ...Query) Aggregation {
+	filter := allOf(filters...)
+	if filter == nil {
+		return agg
+	}
+	return FilterAggregation{Filter: filter, Aggs: map[string]Aggregation{name: agg}}
+}
+
+// hitHighlights reports fragments by document field: a match on name.translit highlights name.
+func hitHighlights(raw map[string][]string) map[string][]string {
+	if len(raw) == 0 {
+		return nil
+	}
+	// sorted, so "name" wins over "name.translit"
+	fields := make([]string, 0, len(raw))
+	for field := range raw {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	highlights := make(map[string][]string, len(raw))
+	for _, field := range fields {
+		base, _, _ := strings.Cut(field, ".")
+		if _, ok := highlights[base]; !ok {
+			highlights[base] = raw[field]
+		}
+	}
+	return highlights
 }
 
-func (r *ElasticRepository) SearchGames(ctx context.Context, query string) ([]Game, error) {
-	q := fmt.Sprintf(`{
-	  "query": {
-	    "multi_match": {
-	      "query": "%s",
-	      "fields": ["name^3", "description"]
-	    }
-	  }
-	}`, query)
+type aggregationBuckets struct {
+	Buckets []struct {
+		Key      any `json:"key"`
+		DocCount int `json:"doc_count"`
+	} `json:"buckets"`
+	// set when the buckets are nested in a filter aggregation
+	Genres  *aggregationBuckets `json:"genres"`
+	Ratings *aggregationBuckets `json:"ratings"`
+	Tags    *aggregationBuckets `json:"tags"`
+}
+
+func (a aggregationBuckets) facet() []FacetBucket {
+	if a.Genres != nil {
+		return a.Genres.facet()
+	}
+	if a.Ratings != nil {
+		return a.Ratings.facet()
+	}
+	if a.Tags != nil {
+		return a.Tags.facet()
+	}
+	buckets := make([]FacetBucket, 0, len(a.Buckets))
+	for _, b := range a.Buckets {
+		buckets = append(buckets, FacetBucket{Key: fmt.Sprint(b.Key), Count: b.DocCount})
+	}
+	return buckets
+}
+
+func (r *ElasticRepository) SearchGames(ctx context.Context, req SearchRequest) (*SearchResult, error) {
+	q, err := json.Marshal(buildSearchBody(req))
+	if err != nil {
+		return nil, err
+	}
 
 	res, err := r.es.Search(
 		r.es.Search.WithContext(ctx),
-		r.es.Search.WithIndex("games"),
-		r.es.Search.WithBody(bytes.NewReader([]byte(q))),
+		r.es.Search.WithIndex(gamesAlias),
+		r.es.Search.WithBody(bytes.NewReader(q)),
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer res.Body.Close()
+	if res.IsError() {
+		return nil, readError(res)
+	}
 
 	var resp struct {
 		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
 			Hits []struct {
-				Source Game `json:"_source"`
+				Score     *float64            `json:"_score"`
+				Source    Game                `json:"_source"`
+				Highlight map[string][]string `json:"highlight"`
 			} `json:"hits"`
 		} `json:"hits"`
+		Aggregations struct {
+			Genres  aggregationBuckets `json:"genres"`
+			Tags    aggregationBuckets `json:"tags"`
+			Ratings aggregationBuckets `json:"ratings"`
+		} `json:"aggregations"`
 	}
 	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
 		return nil, err
 	}
 
-	games := make([]Game, 0, len(resp.Hits.Hits))
+	result := &SearchResult{
+		Hits:  make([]SearchHit, 0, len(resp.Hits.Hits)),
+		Total: resp.Hits.Total.Value,
+		Facets: Facets{
+			Genres:  resp.Aggregations.Genres.facet(),
+			Tags:    resp.Aggregations.Tags.facet(),
+			Ratings: resp.Aggregations.Ratings.facet(),
+		},
+	}
 	for _, hit := range resp.Hits.Hits {
-		games = append(games, hit.Source)
+		result.Hits = append(result.Hits, SearchHit{
+			Game:       hit.Source,
+			Score:      hit.Score,
+			Highlights: hitHighlights(hit.Highlight),
+		})
 	}
-	return games, nil
+	return result, nil
 }
 
-func (r *ElasticRepository) SyncWith(ctx context.Context, repo Repository) error {
-	query := `{"query": {"match_all": {}}}`
+func buildSuggestBody(prefix string, size int) SearchBody {
+	return SearchBody{
+		Suggest: map[string]Suggester{
+			"names": CompletionSuggester{Prefix: prefix, Field: "name_suggest", Size: size, SkipDuplicates: true},
+		},
+		Source: []string{"id", "name", "image_url"},
+	}
+}
 
-	res, err := r.es.DeleteByQuery(
-		[]string{"games"},
-		strings.NewReader(query),
-		r.es.DeleteByQuery.WithContext(context.Background()),
-	)
+func (r *ElasticRepository) SuggestGames(ctx context.Context, prefix string, size int) ([]Suggestion, error) {
+	q, err := json.Marshal(buildSuggestBody(prefix, size))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer res.Body.Close()
 
+	res, err := r.es.Search(
+		r.es.Search.WithContext(ctx),
+		r.es.Search.WithIndex(gamesAlias),
+		r.es.Search.WithBody(bytes.NewReader(q)),
+	)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer res.Body.Close()
 	if res.IsError() {
-		return errors.New(res.String())
+		return nil, readError(res)
 	}
 
-	games, err := repo.GetAllGames(ctx)
-	if err != nil {
-		return err
+	var resp struct {
+		Suggest struct {
+			Names []struct {
+				Options []struct {
+					Source Suggestion `json:"_source"`
+				} `json:"options"`
+			} `json:"names"`
+		} `json:"suggest"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, err
 	}
 
-	for _, v := range games {
-		err := r.IndexGame(ctx, &v)
-		if err != nil {
-			return err
+	suggestions := []Suggestion{}
+	for _, entry := range resp.Suggest.Names {
+		for _, option := range entry.Options {
+			suggestions = append(suggestions, option.Source)
 		}
 	}
-	return nil
+	return suggestions, nil
+}
+
+func buildSimilarBody(id int, size int) SearchBody {
+	gameID := strconv.Itoa(id)
+	return SearchBody{
+		Query: BoolQuery{
+			Must: []Query{MoreLikeThisQuery{
+				Fields:        []string{"name", "description", "reviews_text", "genres.name", "tags.name"},
+				LikeIDs:       []string{gameID},
+				MinTermFreq:   1,
+				MinDocFreq:    1,
+				MaxQueryTerms: 25,
+			}},
+			MustNot: []Query{IDsQuery{Values: []string{gameID}}},
+		},
+		Size: size,
+	}
+}
+
+func (r *ElasticRepository) SimilarGames(ctx context.Context, id int, size int) ([]Game, error) {
+	q, err := json.Marshal(buildSimilarBody(id, size))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.es.Search(
+		r.es.Search.WithContext(ctx),
+		r.es.Search.WithIndex(gamesAlias),
+		r.es.Search.WithBody(bytes.NewReader(q)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, readError(res)
+	}
+
+	var resp struct {
+		Hits struct {
+			Hits []struct {
+				Source Game `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, err
+	}
+	games := make([]Game, 0, len(resp.Hits.Hits))
+	for _, hit := range resp.Hits.Hits {
+		games = append(games, hit.Source)
+	}
+	return games, nil
 }
@@ -0,0 +1,110 @@
+package game
+
+import (
+	"context"
+	"igropoisk_backend/internal/logger"
+	"sync/atomic"
+	"time"
+)
+
+const healthCheckInterval = 10 * time.Second
+
+// FallbackSearchRepository serves reads from primary while it is healthy and from fallback
+// otherwise; a failed read is also retried on fallback. Writes always go to primary,
+// since the outbox retries them until it is back.
+type FallbackSearchRepository struct {
+	primary  SearchRepository
+	fallback SearchRepository
+	healthy  atomic.Bool
+}
+
+func NewFallbackSearchRepository(primary, fallback SearchRepository) *FallbackSearchRepository {
+	r := &FallbackSearchRepository{primary: primary, fallback: fallback}
+	r.healthy.Store(true)
+	return r
+}
+
+// Run health-checks primary until ctx is cancelled.
+func (r *FallbackSearchRepository) Run(ctx context.Context) {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		r.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *FallbackSearchRepository) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckInterval/2)
+	defer cancel()
+	err := r.primary.Ping(ctx)
+	healthy := err == nil
+	if r.healthy.Swap(healthy) != healthy {
+		if healthy {
+			logger.Logger.Info("Search backend recovered")
+		} else {
+			logger.Logger.Warn("Search backend unhealthy, using fallback",
+				"error", err)
+		}
+	}
+}
+
+func (r *FallbackSearchRepository) read(ctx context.Context, op string, fn func(SearchRepository) error) error {
+	if r.healthy.Load() {
+		err := fn(r.primary)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		logger.Logger.Warn("Search backend failed, using fallback",
+			"operation", op,
+			"error", err)
+	}
+	return fn(r.fallback)
+}
+
+func (r *FallbackSearchRepository) IndexGame(ctx context.Context, game *Game) error {
+	return r.primary.IndexGame(ctx, game)
+}
+
+func (r *FallbackSearchRepository) DeleteGame(ctx context.Context, id int) error {
+	return r.primary.DeleteGame(ctx, id)
+}
+
+func (r *FallbackSearchRepository) Ping(ctx context.Context) error {
+	if err := r.primary.Ping(ctx); err != nil {
+		return r.fallback.Ping(ctx)
+	}
+	return nil
+}
+
+func (r *FallbackSearchRepository) SearchGames(ctx context.Context, req SearchRequest) (result *SearchResult, err error) {
+	err = r.read(ctx, "search", func(repo SearchRepository) error {
+		result, err = repo.SearchGames(ctx, req)
+		return err
+	})
+	return result, err
+}
+
+func (r *FallbackSearchRepository) SuggestGames(ctx context.Context, prefix string, size int) (suggestions []Suggestion, err error) {
+	err = r.read(ctx, "suggest", func(repo SearchRepository) error {
+		suggestions, err = repo.SuggestGames(ctx, prefix, size)
+		return err
+	})
+	return suggestions, err
+}
+
+func (r *FallbackSearchRepository) SimilarGames(ctx context.Context, id int, size int) (games []Game, err error) {
+	err = r.read(ctx, "similar", func(repo SearchRepository) error {
+		games, err = repo.SimilarGames(ctx, id, size)
+		return err
+	})
+	return games, err
+}
+
+func (r *FallbackSearchRepository) Reindex(ctx context.Context, repo Repository) (*ReindexResult, error) {
+	return r.primary.Reindex(ctx, repo)
+}
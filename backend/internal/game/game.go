@@ -1,17 +1,24 @@
 package game
 
-import "igropoisk_backend/internal/game/genre"
+import (
+	"igropoisk_backend/internal/game/genre"
+	"igropoisk_backend/internal/game/tag"
+)
 
 const MinReviews = 3
 
 type Game struct {
-	ID           int         `json:"id"`
-	Name         string      `json:"name"`
-	AvgRating    *float64    `json:"avg_rating"` // may be nil
-	ReviewsCount int         `json:"reviews_count"`
-	Description  string      `json:"description"`
-	ImageURL     string      `json:"image_url"`
-	Genre        genre.Genre `json:"genre"`
+	ID           int           `json:"id"`
+	Name         string        `json:"name"`
+	AvgRating    *float64      `json:"avg_rating"` // may be nil
+	ReviewsCount int           `json:"reviews_count"`
+	Description  string        `json:"description"`
+	ImageURL     string        `json:"image_url"`
+	Genres       []genre.Genre `json:"genres"` // by name
+	Tags         []tag.Tag     `json:"tags"`   // by name
+	// ReviewsText joins the latest review texts for the search index. Reads leave
+	// it empty except StreamGames; Repository.GetReviewsText loads it for one game.
+	ReviewsText string `json:"-"`
 }
 
 func (g *Game) Average() *float64 {
@@ -20,3 +27,26 @@ func (g *Game) Average() *float64 {
 	}
 	return g.AvgRating
 }
+
+func (g *Game) GenreNames() []string {
+	names := make([]string, len(g.Genres))
+	for i, ge := range g.Genres {
+		names[i] = ge.Name
+	}
+	return names
+}
+
+func (g *Game) TagNames() []string {
+	names := make([]string, len(g.Tags))
+	for i, t := range g.Tags {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// Suggestion is the lightweight form of a game returned by autocomplete.
+type Suggestion struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	ImageURL string `json:"image_url"`
+}
@@ -0,0 +1,82 @@
+package genre
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"slices"
+	"sync"
+)
+
+// MemoryRepository keeps genres in memory for tests and local development.
+type MemoryRepository struct {
+	mu     sync.RWMutex
+	genres []Genre
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{}
+}
+
+func (m *MemoryRepository) GetGenreByID(ctx context.Context, id int) (*Genre, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, g := range m.genres {
+		if g.ID == id {
+			return &g, nil
+		}
+	}
+	return &Genre{}, fmt.Errorf("GetGenreByID: %w", pgx.ErrNoRows)
+}
+
+func (m *MemoryRepository) GetGenreByName(ctx context.Context, name string) (*Genre, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, g := range m.genres {
+		if g.Name == name {
+			return &g, nil
+		}
+	}
+	return &Genre{}, fmt.Errorf("GetGenreByName: %w", pgx.ErrNoRows)
+}
+
+func (m *MemoryRepository) AddGenre(ctx context.Context, name string) (*Genre, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, g := range m.genres {
+		if g.Name == name {
+			return &Genre{}, fmt.Errorf("AddGenre: genre %q already exists", name)
+		}
+	}
+	g := Genre{ID: len(m.genres) + 1, Name: name}
+	m.genres = append(m.genres, g)
+	return &g, nil
+}
+
+func (m *MemoryRepository) ListGenres(ctx context.Context) ([]Genre, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	genres := slices.Clone(m.genres)
+	slices.SortFunc(genres, func(a, b Genre) int { return cmp.Compare(a.Name, b.Name) })
+	return genres, nil
+}
+
+func (m *MemoryRepository) UpsertGenres(ctx context.Context, names []string) ([]Genre, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	genres := make([]Genre, 0, len(names))
+next:
+	for _, name := range names {
+		for _, g := range m.genres {
+			if g.Name == name {
+				genres = append(genres, g)
+				continue next
+			}
+		}
+		g := Genre{ID: len(m.genres) + 1, Name: name}
+		m.genres = append(m.genres, g)
+		genres = append(genres, g)
+	}
+	return genres, nil
+}
@@ -0,0 +1,3 @@
+INSERT INTO genres (name) SELECT unnest($1::text[])
+ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+RETURNING id, name
\ No newline at end of file
@@ -3,6 +3,8 @@ package genre
 import (
 	"context"
 	_ "embed"
+	"fmt"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -15,10 +17,19 @@ var getGenreByNameSQL string
 //go:embed queries/add_genre.sql
 var addGenreSQL string
 
+//go:embed queries/list_genres.sql
+var listGenresSQL string
+
+//go:embed queries/upsert_genres.sql
+var upsertGenresSQL string
+
 type Repository interface {
 	GetGenreByID(ctx context.Context, id int) (*Genre, error)
 	GetGenreByName(ctx context.Context, name string) (*Genre, error)
 	AddGenre(ctx context.Context, name string) (*Genre, error)
+	ListGenres(ctx context.Context) ([]Genre, error)
+	// UpsertGenres returns the genres named names, creating the missing ones. Names must be distinct.
+	UpsertGenres(ctx context.Context, names []string) ([]Genre, error)
 }
 
 type PostgresRepository struct {
@@ -48,3 +59,41 @@ func (p *PostgresRepository) AddGenre(ctx context.Context, name string) (*Genre,
 	g.Name = name
 	return &g, err
 }
+
+func (p *PostgresRepository) ListGenres(ctx context.Context) ([]Genre, error) {
+	rows, err := p.pool.Query(ctx, listGenresSQL)
+	if err != nil {
+		return nil, fmt.Errorf("ListGenres: %w", err)
+	}
+	genres, err := pgx.CollectRows(rows, pgx.RowToStructByPos[Genre])
+	if err != nil {
+		return nil, fmt.Errorf("ListGenres: %w", err)
+	}
+	return genres, nil
+}
+
+func (p *PostgresRepository) UpsertGenres(ctx context.Context, names []string) ([]Genre, error) {
+	return upsertGenres(ctx, p.pool, names)
+}
+
+// UpsertGenresTx is UpsertGenres inside tx, so new genres commit or roll back with it.
+func UpsertGenresTx(ctx context.Context, tx pgx.Tx, names []string) ([]Genre, error) {
+	return upsertGenres(ctx, tx, names)
+}
+
+// querier is a pool or a transaction.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func upsertGenres(ctx context.Context, q querier, names []string) ([]Genre, error) {
+	rows, err := q.Query(ctx, upsertGenresSQL, names)
+	if err != nil {
+		return nil, fmt.Errorf("UpsertGenres: %w", err)
+	}
+	genres, err := pgx.CollectRows(rows, pgx.RowToStructByPos[Genre])
+	if err != nil {
+		return nil, fmt.Errorf("UpsertGenres: %w", err)
+	}
+	return genres, nil
+}
@@ -1,10 +1,13 @@
 package game
 
 import (
+	"cmp"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 type Handler struct {
@@ -25,6 +28,10 @@ func (h *Handler) GetGameByID(c *gin.Context) {
 
 	game, err := h.service.GetGameByID(c.Request.Context(), id)
 	if err != nil {
+		if errors.Is(err, ErrGameNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -33,20 +40,66 @@ func (h *Handler) GetGameByID(c *gin.Context) {
 }
 
 func (h *Handler) GetAllGames(c *gin.Context) {
-	games, err := h.service.GetAllGames(c.Request.Context())
+	opts, err := parseListOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.service.GetAllGames(c.Request.Context(), opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get games"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"games": games})
+	c.JSON(http.StatusOK, page)
+}
+
+func parseListOptions(c *gin.Context) (opts ListOptions, err error) {
+	if v := c.Query("genre_id"); v != "" {
+		if opts.GenreID, err = strconv.Atoi(v); err != nil || opts.GenreID <= 0 {
+			return opts, errors.New("invalid genre_id")
+		}
+	}
+	opts.GenreName = c.Query("genre")
+	// stored tags are normalized; repeats would also break the all-tags count in Postgres
+	if opts.Tags, err = normalizeTags(c.QueryArray("tag")); err != nil {
+		return opts, err
+	}
+	if opts.MinRating, err = parseRating(c, "min_rating"); err != nil {
+		return opts, err
+	}
+	if v := c.Query("min_reviews"); v != "" {
+		if opts.MinReviews, err = strconv.Atoi(v); err != nil || opts.MinReviews < 0 {
+			return opts, errors.New("invalid min_reviews")
+		}
+	}
+	opts.Sort = SortOrder(c.DefaultQuery("sort", string(SortByName)))
+	if !opts.Sort.Valid() {
+		return opts, errors.New("sort must be one of name, avg_rating, reviews_count, newest")
+	}
+	if v := c.Query("limit"); v != "" {
+		if opts.Limit, err = strconv.Atoi(v); err != nil || opts.Limit <= 0 || opts.Limit > MaxPageSize {
+			return opts, fmt.Errorf("limit must be between 1 and %d", MaxPageSize)
+		}
+	}
+	if v := c.Query("cursor"); v != "" {
+		if opts.After, err = DecodeCursor(v); err != nil {
+			return opts, err
+		}
+		if opts.After.Sort != opts.Sort {
+			return opts, errors.New("cursor does not match sort order")
+		}
+	}
+	return opts, nil
 }
 
 type AddGameRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	ImageURL    string `json:"image_url"`
-	Genre       string `json:"genre"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	ImageURL    string   `json:"image_url"`
+	Genres      []string `json:"genres"`
+	Tags        []string `json:"tags"`
 }
 
 func (h *Handler) AddGame(c *gin.Context) {
@@ -61,6 +114,10 @@ func (h *Handler) AddGame(c *gin.Context) {
 	}
 
 	err := h.service.AddGame(c.Request.Context(), req)
+	if errors.Is(err, ErrGameExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -76,12 +133,104 @@ func validateAddGameRequest(request AddGameRequest) (err error) {
 		err = errors.New("description is required")
 	case len(request.ImageURL) == 0:
 		err = errors.New("image_url is required")
-	case len(request.Genre) == 0:
-		err = errors.New("genre is required")
+	case len(request.Genres) == 0:
+		err = errors.New("genres is required")
+	}
+	return err
+}
+
+type UpdateGameRequest struct {
+	Name        *string   `json:"name"`
+	Description *string   `json:"description"`
+	ImageURL    *string   `json:"image_url"`
+	Genres      *[]string `json:"genres"`
+	Tags        *[]string `json:"tags"`
+}
+
+// UpdateGame handles PATCH: only the fields present in the body are changed.
+func (h *Handler) UpdateGame(c *gin.Context) {
+	h.updateGame(c, false)
+}
+
+// ReplaceGame handles PUT: every field must be present, except tags, which are cleared when left out.
+func (h *Handler) ReplaceGame(c *gin.Context) {
+	h.updateGame(c, true)
+}
+
+func (h *Handler) updateGame(c *gin.Context, replace bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game id"})
+		return
+	}
+	var req UpdateGameRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if err := validateUpdateGameRequest(req, replace); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if replace && req.Tags == nil {
+		req.Tags = &[]string{}
+	}
+
+	game, err := h.service.UpdateGame(c.Request.Context(), id, req)
+	if err != nil {
+		if errors.Is(err, ErrGameNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, ErrGameExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, game)
+}
+
+func validateUpdateGameRequest(request UpdateGameRequest, replace bool) (err error) {
+	if replace {
+		return validateAddGameRequest(AddGameRequest{
+			Name:        deref(request.Name),
+			Description: deref(request.Description),
+			ImageURL:    deref(request.ImageURL),
+			Genres:      derefSlice(request.Genres),
+			Tags:        derefSlice(request.Tags),
+		})
+	}
+	switch {
+	case request.Name == nil && request.Description == nil && request.ImageURL == nil && request.Genres == nil && request.Tags == nil:
+		err = errors.New("nothing to update")
+	case request.Name != nil && len(*request.Name) == 0:
+		err = errors.New("name must not be empty")
+	case request.Description != nil && len(*request.Description) == 0:
+		err = errors.New("description must not be empty")
+	case request.ImageURL != nil && len(*request.ImageURL) == 0:
+		err = errors.New("image_url must not be empty")
+	case request.Genres != nil && len(*request.Genres) == 0:
+		err = errors.New("genres must not be empty")
 	}
 	return err
 }
 
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func derefSlice(s *[]string) []string {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
 func (h *Handler) DeleteGameByID(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
@@ -100,15 +249,251 @@ func (h *Handler) DeleteGameByID(c *gin.Context) {
 }
 
 func (h *Handler) SearchGame(c *gin.Context) {
-	query := c.Query("query")
-	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "query is required"})
+	req, err := parseSearchRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	result, err := h.service.SearchGames(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) Reindex(c *gin.Context) {
+	result, err := h.service.Reindex(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "result": result})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// maxImportBody bounds an import upload, about MaxImportRows rows with long descriptions.
+const maxImportBody = 64 << 20
+
+// ImportGames takes a JSON Lines or CSV catalog dump, the format comes from the format
+// query parameter or the Content-Type.
+func (h *Handler) ImportGames(c *gin.Context) {
+	format := c.Query("format")
+	if format == "" {
+		switch c.ContentType() {
+		case "text/csv":
+			format = string(ImportCSV)
+		case "application/x-ndjson", "application/jsonl":
+			format = string(ImportJSONLines)
+		}
+	}
+	importFormat, err := ParseImportFormat(format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	games, err := h.service.SearchGames(c.Request.Context(), query)
+	records, err := DecodeImport(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBody), importFormat)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("import is larger than %d bytes", tooLarge.Limit)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	report, err := h.service.ImportGames(c.Request.Context(), records)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *Handler) SimilarGames(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game id"})
+		return
+	}
+	size := 0
+	if v := c.Query("limit"); v != "" {
+		if size, err = strconv.Atoi(v); err != nil || size <= 0 || size > MaxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", MaxPageSize)})
+			return
+		}
+	}
+	games, err := h.service.SimilarGames(c.Request.Context(), id, size)
+	if err != nil {
+		if errors.Is(err, ErrGameNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"games": games})
 }
+
+func (h *Handler) SuggestGames(c *gin.Context) {
+	prefix := strings.TrimSpace(c.Query("prefix"))
+	if prefix == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "prefix is required"})
+		return
+	}
+	size := 0
+	if v := c.Query("limit"); v != "" {
+		var err error
+		if size, err = strconv.Atoi(v); err != nil || size <= 0 || size > MaxSuggestions {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", MaxSuggestions)})
+			return
+		}
+	}
+	suggestions, err := h.service.SuggestGames(c.Request.Context(), prefix, size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
+
+func parseRating(c *gin.Context, param string) (*float64, error) {
+	v := c.Query(param)
+	if v == "" {
+		return nil, nil
+	}
+	rating, err := strconv.ParseFloat(v, 64)
+	if err != nil || rating < 0 || rating > 10 {
+		return nil, fmt.Errorf("%s must be between 0 and 10", param)
+	}
+	return &rating, nil
+}
+
+func parseSearchRequest(c *gin.Context) (req SearchRequest, err error) {
+	req.Query = c.Query("query")
+	if req.Query == "" {
+		return req, errors.New("query is required")
+	}
+	req.Genres = c.QueryArray("genre")
+	if req.Tags, err = normalizeTags(c.QueryArray("tag")); err != nil {
+		return req, err
+	}
+	if req.MinRating, err = parseRating(c, "min_rating"); err != nil {
+		return req, err
+	}
+	if req.MaxRating, err = parseRating(c, "max_rating"); err != nil {
+		return req, err
+	}
+	if req.MinRating != nil && req.MaxRating != nil && *req.MinRating > *req.MaxRating {
+		return req, errors.New("min_rating must not exceed max_rating")
+	}
+	if v := c.Query("min_reviews"); v != "" {
+		if req.MinReviews, err = strconv.Atoi(v); err != nil || req.MinReviews < 0 {
+			return req, errors.New("invalid min_reviews")
+		}
+	}
+	if v := c.Query("sort"); v != "" {
+		if req.Sort = SortOrder(v); !req.Sort.Valid() {
+			return req, errors.New("sort must be one of name, avg_rating, reviews_count, newest")
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if req.Size, err = strconv.Atoi(v); err != nil || req.Size <= 0 || req.Size > MaxPageSize {
+			return req, fmt.Errorf("limit must be between 1 and %d", MaxPageSize)
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if req.From, err = strconv.Atoi(v); err != nil || req.From < 0 {
+			return req, errors.New("invalid offset")
+		}
+	}
+	if req.From+cmp.Or(req.Size, DefaultPageSize) > MaxSearchWindow {
+		return req, fmt.Errorf("offset plus limit must not exceed %d", MaxSearchWindow)
+	}
+	return req, nil
+}
+
+type OutboxHandler struct {
+	dispatcher *OutboxDispatcher
+}
+
+func NewOutboxHandler(dispatcher *OutboxDispatcher) *OutboxHandler {
+	return &OutboxHandler{dispatcher: dispatcher}
+}
+
+func (h *OutboxHandler) GetLag(c *gin.Context) {
+	lag, err := h.dispatcher.Lag(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get search sync lag"})
+		return
+	}
+	c.JSON(http.StatusOK, lag)
+}
+
+func (h *Handler) ListGenres(c *gin.Context) {
+	genres, err := h.service.ListGenres(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"genres": genres})
+}
+
+func (h *Handler) ListTags(c *gin.Context) {
+	tags, err := h.service.ListTags(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+type AddGameTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+func (h *Handler) AddGameTags(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game id"})
+		return
+	}
+	var req AddGameTagsRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if len(req.Tags) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tags is required"})
+		return
+	}
+
+	game, err := h.service.AddGameTags(c.Request.Context(), id, req.Tags)
+	if err != nil {
+		if errors.Is(err, ErrGameNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, game)
+}
+
+func (h *Handler) RemoveGameTag(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game id"})
+		return
+	}
+
+	game, err := h.service.RemoveGameTag(c.Request.Context(), id, c.Param("tag"))
+	if err != nil {
+		if errors.Is(err, ErrGameNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, game)
+}
@@ -0,0 +1,207 @@
+package game
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+type ImportFormat string
+
+const (
+	ImportJSONLines ImportFormat = "jsonl"
+	ImportCSV       ImportFormat = "csv"
+)
+
+// MaxImportRows bounds a single import, larger catalogs are imported in parts.
+const MaxImportRows = 50000
+
+// ImportRecord is one row of a catalog dump; Err is set when the row could not be decoded.
+type ImportRecord struct {
+	Line    int
+	Request AddGameRequest
+	Err     error
+}
+
+type ImportStatus string
+
+const (
+	ImportCreated ImportStatus = "created"
+	ImportSkipped ImportStatus = "skipped"
+	ImportFailed  ImportStatus = "failed"
+)
+
+type ImportRowResult struct {
+	Line   int          `json:"line"`
+	Name   string       `json:"name"`
+	Status ImportStatus `json:"status"`
+	ID     int          `json:"id,omitempty"`
+	Reason string       `json:"reason,omitempty"`
+}
+
+type ImportReport struct {
+	Created int               `json:"created"`
+	Skipped int               `json:"skipped"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+func (r *ImportReport) count() {
+	for _, row := range r.Rows {
+		switch row.Status {
+		case ImportCreated:
+			r.Created++
+		case ImportSkipped:
+			r.Skipped++
+		case ImportFailed:
+			r.Failed++
+		}
+	}
+}
+
+// ParseImportFormat accepts a format name or a file name with a .jsonl, .ndjson or .csv extension.
+func ParseImportFormat(s string) (ImportFormat, error) {
+	s = strings.ToLower(s)
+	switch {
+	case s == "jsonl" || s == "ndjson" || strings.HasSuffix(s, ".jsonl") || strings.HasSuffix(s, ".ndjson"):
+		return ImportJSONLines, nil
+	case s == "csv" || strings.HasSuffix(s, ".csv"):
+		return ImportCSV, nil
+	}
+	return "", fmt.Errorf("unknown import format %q, use jsonl or csv", s)
+}
+
+// DecodeImport reads every row of r. Rows that cannot be decoded are returned with Err
+// set, the error is only for input that cannot be read at all.
+func DecodeImport(r io.Reader, format ImportFormat) ([]ImportRecord, error) {
+	var (
+		records []ImportRecord
+		err     error
+	)
+	switch format {
+	case ImportJSONLines:
+		records, err = decodeJSONLines(r)
+	case ImportCSV:
+		records, err = decodeCSV(r)
+	default:
+		return nil, fmt.Errorf("unknown import format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > MaxImportRows {
+		return nil, fmt.Errorf("too many rows, at most %d can be imported at once", MaxImportRows)
+	}
+	return records, nil
+}
+
+func decodeJSONLines(r io.Reader) ([]ImportRecord, error) {
+	var records []ImportRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		record := ImportRecord{Line: line}
+		dec := json.NewDecoder(strings.NewReader(scanner.Text()))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&record.Request); err != nil {
+			record.Err = fmt.Errorf("invalid json: %w", err)
+		}
+		records = append(records, record)
+		if len(records) > MaxImportRows {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+var (
+	csvColumns         = []string{"name", "description", "image_url", "genres", "tags"}
+	optionalCSVColumns = []string{"tags"}
+	// csvAliases keeps dumps from before games had several genres importable
+	csvAliases = map[string]string{"genre": "genres"}
+)
+
+// csvListSeparator separates the genres and tags of a cell, as in "RPG;Open World".
+const csvListSeparator = ";"
+
+func splitCSVList(cell string) []string {
+	if strings.TrimSpace(cell) == "" {
+		return nil
+	}
+	return strings.Split(cell, csvListSeparator)
+}
+
+// decodeCSV expects a header naming the AddGameRequest fields, in any order.
+func decodeCSV(r io.Reader) ([]ImportRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if alias, ok := csvAliases[name]; ok {
+			name = alias
+		}
+		if !slices.Contains(csvColumns, name) {
+			return nil, fmt.Errorf("unknown csv column %q, expected %s", name, strings.Join(csvColumns, ", "))
+		}
+		columns[name] = i
+	}
+	for _, name := range csvColumns {
+		if _, ok := columns[name]; !ok && !slices.Contains(optionalCSVColumns, name) {
+			return nil, fmt.Errorf("csv header has no %q column", name)
+		}
+	}
+
+	var records []ImportRecord
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var record ImportRecord
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			record.Line, record.Err = parseErr.StartLine, fmt.Errorf("invalid csv: %w", parseErr.Err)
+		case err != nil:
+			return nil, err
+		case len(row) != len(header):
+			record.Line, _ = reader.FieldPos(0)
+			record.Err = fmt.Errorf("expected %d fields, got %d", len(header), len(row))
+		default:
+			record.Line, _ = reader.FieldPos(0)
+			record.Request = AddGameRequest{
+				Name:        row[columns["name"]],
+				Description: row[columns["description"]],
+				ImageURL:    row[columns["image_url"]],
+				Genres:      splitCSVList(row[columns["genres"]]),
+			}
+			if i, ok := columns["tags"]; ok {
+				record.Request.Tags = splitCSVList(row[i])
+			}
+		}
+		records = append(records, record)
+		if len(records) > MaxImportRows {
+			break
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,92 @@
+package game
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDecodeImportCSV(t *testing.T) {
+	input := "genre,name,image_url,description\n" +
+		"RPG,Elden Ring,img.png,\"Open world, souls-like\"\n" +
+		"RPG,Broken\n" +
+		"Puzzle,Tetris,img.png,Falling blocks\n"
+	records, err := DecodeImport(strings.NewReader(input), ImportCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+	if r := records[0]; r.Err != nil || r.Line != 2 || r.Request.Name != "Elden Ring" || r.Request.Description != "Open world, souls-like" {
+		t.Fatalf("records[0] = %+v", r)
+	}
+	if r := records[1]; r.Err == nil || r.Line != 3 {
+		t.Fatalf("records[1] = %+v, want a field count error on line 3", r)
+	}
+	if r := records[2]; r.Err != nil || r.Line != 4 || len(r.Request.Genres) != 1 || r.Request.Genres[0] != "Puzzle" {
+		t.Fatalf("records[2] = %+v", r)
+	}
+
+	records, err = DecodeImport(strings.NewReader("name,description,image_url,genres,tags\n"+
+		"Elden Ring,Souls-like,img.png,RPG;Open World,souls-like;Dark Fantasy\n"), ImportCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := records[0]; len(r.Request.Genres) != 2 || len(r.Request.Tags) != 2 || r.Request.Tags[1] != "Dark Fantasy" {
+		t.Fatalf("records[0] = %+v", r)
+	}
+
+	if _, err := DecodeImport(strings.NewReader("name,genre\n"), ImportCSV); err == nil {
+		t.Fatal("accepted a header without description and image_url")
+	}
+}
+
+func TestImportGames(t *testing.T) {
+	s, _ := newTestService(t)
+	input := `{"name":"elden ring","description":"Souls-like","image_url":"img.png","genres":["RPG","Open World"],"tags":["Souls-like"]}
+{"name":"ELDEN RING","description":"Again","image_url":"img.png","genres":["RPG"]}
+{"name":"Doom","description":"Already in the catalog","image_url":"img.png","genres":["Shooter"]}
+{"name":"Tetris","description":"Falling blocks","image_url":"img.png","genres":["Puzzle"]}
+
+{"name":"X","description":"Too short","image_url":"img.png","genres":["Puzzle"]}
+{"name":"Broken",
+{"title":"Unknown field"}
+`
+	records, err := DecodeImport(strings.NewReader(input), ImportJSONLines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := s.ImportGames(context.Background(), records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Created != 2 || report.Skipped != 2 || report.Failed != 3 {
+		t.Fatalf("report = %+v, want 2 created, 2 skipped, 3 failed", report)
+	}
+	want := []struct {
+		line   int
+		status ImportStatus
+	}{
+		{1, ImportCreated}, {2, ImportSkipped}, {3, ImportSkipped}, {4, ImportCreated},
+		{6, ImportFailed}, {7, ImportFailed}, {8, ImportFailed},
+	}
+	for i, w := range want {
+		if row := report.Rows[i]; row.Line != w.line || row.Status != w.status {
+			t.Fatalf("rows[%d] = %+v, want line %d %s", i, row, w.line, w.status)
+		}
+	}
+
+	game, err := s.GetGameByName(context.Background(), "elden ring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.ID != report.Rows[0].ID || !slices.Equal(game.GenreNames(), []string{"Open World", "RPG"}) ||
+		!slices.Equal(game.TagNames(), []string{"souls-like"}) {
+		t.Fatalf("imported game = %+v", game)
+	}
+	if game.Genres[0].ID == 0 || game.Tags[0].ID == 0 {
+		t.Fatalf("imported genres and tags have no ids: %+v", game)
+	}
+}
@@ -0,0 +1,138 @@
+package game
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"slices"
+)
+
+// IndexStreamer is implemented by search backends that keep their own copy of the
+// catalog, which can then drift from the database.
+type IndexStreamer interface {
+	StreamIndexedGames(ctx context.Context, fn func(Game) error) error
+}
+
+// ErrIndexNotVerifiable is returned for search backends that read the database directly.
+var ErrIndexNotVerifiable = errors.New("the search backend reads the database directly, there is no index to verify")
+
+// IndexDiff lists the games whose index documents do not match the database.
+type IndexDiff struct {
+	Checked  int   `json:"checked"`
+	Missing  []int `json:"missing"`  // in the database, not in the index
+	Stale    []int `json:"stale"`    // indexed with outdated values
+	Orphaned []int `json:"orphaned"` // in the index, not in the database
+}
+
+func (d *IndexDiff) InSync() bool {
+	return len(d.Missing) == 0 && len(d.Stale) == 0 && len(d.Orphaned) == 0
+}
+
+// DiffIndex compares every game of repo with its document in index.
+func DiffIndex(ctx context.Context, repo Repository, index IndexStreamer) (*IndexDiff, error) {
+	indexed := map[int]Game{}
+	err := index.StreamIndexedGames(ctx, func(g Game) error {
+		indexed[g.ID] = g
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &IndexDiff{Missing: []int{}, Stale: []int{}, Orphaned: []int{}}
+	err = repo.StreamGames(ctx, func(g Game) error {
+		diff.Checked++
+		doc, ok := indexed[g.ID]
+		switch {
+		case !ok:
+			diff.Missing = append(diff.Missing, g.ID)
+		case !sameDocument(g, doc):
+			diff.Stale = append(diff.Stale, g.ID)
+		}
+		delete(indexed, g.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for id := range indexed {
+		diff.Orphaned = append(diff.Orphaned, id)
+	}
+	slices.Sort(diff.Orphaned)
+	return diff, nil
+}
+
+func sameDocument(a, b Game) bool {
+	ratingsEqual := (a.AvgRating == nil) == (b.AvgRating == nil) &&
+		(a.AvgRating == nil || math.Abs(*a.AvgRating-*b.AvgRating) < 0.005)
+	return ratingsEqual &&
+		a.Name == b.Name &&
+		a.Description == b.Description &&
+		a.ImageURL == b.ImageURL &&
+		a.ReviewsCount == b.ReviewsCount &&
+		slices.Equal(a.Genres, b.Genres) &&
+		slices.Equal(a.Tags, b.Tags)
+}
+
+const streamPageSize = 1000
+
+// StreamIndexedGames pages through the alias by id.
+func (r *ElasticRepository) StreamIndexedGames(ctx context.Context, fn func(Game) error) error {
+	body := SearchBody{
+		Query:  MatchAllQuery{},
+		Size:   streamPageSize,
+		Sort:   []SortField{{Field: "id", Order: "asc"}},
+		Source: []string{"id", "name", "avg_rating", "reviews_count", "description", "image_url", "genres", "tags"},
+	}
+	for {
+		q, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		res, err := r.es.Search(
+			r.es.Search.WithContext(ctx),
+			r.es.Search.WithIndex(gamesAlias),
+			r.es.Search.WithBody(bytes.NewReader(q)),
+		)
+		if err != nil {
+			return err
+		}
+		var resp struct {
+			Hits struct {
+				Hits []struct {
+					Source Game `json:"_source"`
+				} `json:"hits"`
+			} `json:"hits"`
+		}
+		if res.IsError() {
+			err = readError(res)
+		} else {
+			err = json.NewDecoder(res.Body).Decode(&resp)
+		}
+		res.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, hit := range resp.Hits.Hits {
+			if err := fn(hit.Source); err != nil {
+				return err
+			}
+		}
+		if len(resp.Hits.Hits) < streamPageSize {
+			return nil
+		}
+		body.SearchAfter = []any{resp.Hits.Hits[len(resp.Hits.Hits)-1].Source.ID}
+	}
+}
+
+// StreamIndexedGames streams the index of primary, the one being verified.
+func (r *FallbackSearchRepository) StreamIndexedGames(ctx context.Context, fn func(Game) error) error {
+	streamer, ok := r.primary.(IndexStreamer)
+	if !ok {
+		return ErrIndexNotVerifiable
+	}
+	return streamer.StreamIndexedGames(ctx, fn)
+}
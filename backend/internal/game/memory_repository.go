@@ -0,0 +1,348 @@
+package game
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"igropoisk_backend/internal/game/genre"
+	"igropoisk_backend/internal/game/tag"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryRepository keeps games in memory for tests and local development.
+// Missing games are reported with pgx.ErrNoRows, like the Postgres implementation.
+type MemoryRepository struct {
+	mu     sync.RWMutex
+	games  map[int]Game
+	nextID int
+	outbox *MemoryOutboxRepository
+	genres genre.Repository
+	tags   tag.Repository
+}
+
+// NewMemoryRepository records search outbox events in outbox when it is not nil.
+// ImportGames creates the genres and tags it names in genres and tags.
+func NewMemoryRepository(outbox *MemoryOutboxRepository, genres genre.Repository, tags tag.Repository) *MemoryRepository {
+	return &MemoryRepository{games: make(map[int]Game), nextID: 1, outbox: outbox, genres: genres, tags: tags}
+}
+
+// nameTaken reports whether another game than id has name; callers hold the lock.
+func (m *MemoryRepository) nameTaken(name string, id int) bool {
+	for _, g := range m.games {
+		if g.Name == name && g.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemoryRepository) record(gameID int, action OutboxAction) {
+	if m.outbox != nil {
+		m.outbox.add(gameID, action)
+	}
+}
+
+func (m *MemoryRepository) AddGame(ctx context.Context, game *Game) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.nameTaken(game.Name, 0) {
+		return fmt.Errorf("AddGame: %w", ErrGameExists)
+	}
+	game.ID = m.nextID
+	m.nextID++
+	m.games[game.ID] = *game
+	m.record(game.ID, OutboxIndex)
+	return nil
+}
+
+func (m *MemoryRepository) ImportGames(ctx context.Context, games []Game) error {
+	err := resolveLinks(games,
+		func(names []string) ([]genre.Genre, error) { return m.genres.UpsertGenres(ctx, names) },
+		func(names []string) ([]tag.Tag, error) { return m.tags.UpsertTags(ctx, names) })
+	if err != nil {
+		return fmt.Errorf("ImportGames: %w", err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	taken := make(map[string]bool, len(m.games))
+	for _, g := range m.games {
+		taken[g.Name] = true
+	}
+	for i := range games {
+		if taken[games[i].Name] {
+			continue
+		}
+		games[i].ID = m.nextID
+		m.nextID++
+		m.games[games[i].ID] = games[i]
+		m.record(games[i].ID, OutboxIndex)
+	}
+	return nil
+}
+
+// UpdateGame keeps the rating, which only reviews change.
+func (m *MemoryRepository) UpdateGame(ctx context.Context, game *Game) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.games[game.ID]
+	if !ok {
+		return fmt.Errorf("UpdateGame: %w", pgx.ErrNoRows)
+	}
+	if m.nameTaken(game.Name, game.ID) {
+		return fmt.Errorf("UpdateGame: %w", ErrGameExists)
+	}
+	stored.Name = game.Name
+	stored.Description = game.Description
+	stored.ImageURL = game.ImageURL
+	stored.Genres = game.Genres
+	stored.Tags = game.Tags
+	m.games[game.ID] = stored
+	m.record(game.ID, OutboxIndex)
+	return nil
+}
+
+func (m *MemoryRepository) RemoveGameByID(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.games, id)
+	m.record(id, OutboxDelete)
+	return nil
+}
+
+func (m *MemoryRepository) GetGameByID(ctx context.Context, id int) (*Game, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	game, ok := m.games[id]
+	if !ok {
+		return nil, fmt.Errorf("GetGameByID: %w", pgx.ErrNoRows)
+	}
+	return &game, nil
+}
+
+func (m *MemoryRepository) GetGameByName(ctx context.Context, name string) (*Game, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, game := range m.games {
+		if game.Name == name {
+			return &game, nil
+		}
+	}
+	return nil, fmt.Errorf("GetGameByName: %w", pgx.ErrNoRows)
+}
+
+// GetReviewsText is empty: reviews live in the review package's own repository.
+func (m *MemoryRepository) GetReviewsText(ctx context.Context, id int) (string, error) {
+	return "", nil
+}
+
+// UpdateRating stores aggregates and writes an outbox event the way the update_game_rating trigger does.
+func (m *MemoryRepository) UpdateRating(ctx context.Context, gameID, reviewsCount int, avgRating *float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	game, ok := m.games[gameID]
+	if !ok {
+		return nil
+	}
+	game.ReviewsCount = reviewsCount
+	game.AvgRating = avgRating
+	m.games[gameID] = game
+	m.record(gameID, OutboxIndex)
+	return nil
+}
+
+func (m *MemoryRepository) snapshot() []Game {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	games := make([]Game, 0, len(m.games))
+	for _, game := range m.games {
+		games = append(games, game)
+	}
+	slices.SortFunc(games, func(a, b Game) int { return cmp.Compare(a.ID, b.ID) })
+	return games
+}
+
+func (m *MemoryRepository) StreamGames(ctx context.Context, fn func(Game) error) error {
+	for _, game := range m.snapshot() {
+		if err := fn(game); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortKey mirrors the sql keys in sortSpecs; ratings compare as numbers, names as strings.
+func sortKey(order SortOrder, value string) (float64, string) {
+	if order == SortByName {
+		return 0, value
+	}
+	f, _ := strconv.ParseFloat(value, 64)
+	return f, ""
+}
+
+func compareGames(order SortOrder, aValue string, aID int, bValue string, bID int) int {
+	an, as := sortKey(order, aValue)
+	bn, bs := sortKey(order, bValue)
+	c := cmp.Or(cmp.Compare(an, bn), cmp.Compare(as, bs), cmp.Compare(aID, bID))
+	if sortSpecs[order].desc {
+		return -c
+	}
+	return c
+}
+
+func hasAll(names, wanted []string) bool {
+	for _, name := range wanted {
+		if !slices.Contains(names, name) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesListOptions(game Game, opts ListOptions) bool {
+	switch {
+	case opts.GenreID > 0 && !slices.ContainsFunc(game.Genres, func(g genre.Genre) bool { return g.ID == opts.GenreID }):
+		return false
+	case opts.GenreName != "" && !slices.Contains(game.GenreNames(), opts.GenreName):
+		return false
+	case !hasAll(game.TagNames(), opts.Tags):
+		return false
+	case opts.MinRating != nil && (game.AvgRating == nil || *game.AvgRating < *opts.MinRating):
+		return false
+	case opts.MinReviews > 0 && game.ReviewsCount < opts.MinReviews:
+		return false
+	}
+	return true
+}
+
+func (m *MemoryRepository) GetAllGames(ctx context.Context, opts ListOptions) (*Page, error) {
+	spec, ok := sortSpecs[opts.Sort]
+	if !ok {
+		return nil, fmt.Errorf("GetAllGames: unknown sort order %q", opts.Sort)
+	}
+
+	var matched []Game
+	for _, game := range m.snapshot() {
+		if matchesListOptions(game, opts) {
+			matched = append(matched, game)
+		}
+	}
+	slices.SortFunc(matched, func(a, b Game) int {
+		return compareGames(opts.Sort, spec.value(&a), a.ID, spec.value(&b), b.ID)
+	})
+
+	page := &Page{Games: []Game{}, Total: len(matched)}
+	for _, game := range matched {
+		if opts.After != nil && compareGames(opts.Sort, spec.value(&game), game.ID, opts.After.Value, opts.After.ID) <= 0 {
+			continue
+		}
+		if len(page.Games) == opts.Limit {
+			last := &page.Games[len(page.Games)-1]
+			page.NextCursor = Cursor{Sort: opts.Sort, Value: spec.value(last), ID: last.ID}.Encode()
+			break
+		}
+		page.Games = append(page.Games, game)
+	}
+	return page, nil
+}
+
+// MemoryOutboxRepository collects outbox events written by MemoryRepository.
+type MemoryOutboxRepository struct {
+	mu     sync.Mutex
+	events []OutboxEvent
+	done   map[int64]time.Time // when each processed event was processed
+	retry  map[int64]time.Time
+	nextID int64
+}
+
+func NewMemoryOutboxRepository() *MemoryOutboxRepository {
+	return &MemoryOutboxRepository{done: make(map[int64]time.Time), retry: make(map[int64]time.Time), nextID: 1}
+}
+
+func (m *MemoryOutboxRepository) add(gameID int, action OutboxAction) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, OutboxEvent{ID: m.nextID, GameID: gameID, Action: action, CreatedAt: time.Now()})
+	m.nextID++
+}
+
+// ProcessPending calls fn without holding the lock, since fn reads games and
+// MemoryRepository writes to the outbox while holding its own.
+func (m *MemoryOutboxRepository) ProcessPending(ctx context.Context, limit int, fn func(OutboxEvent) (time.Time, error)) (int, error) {
+	m.mu.Lock()
+	var pending []OutboxEvent
+	for _, event := range m.events {
+		if len(pending) == limit {
+			break
+		}
+		if _, done := m.done[event.ID]; !done && !time.Now().Before(m.retry[event.ID]) {
+			pending = append(pending, event)
+			m.retry[event.ID] = time.Now().Add(outboxClaimTimeout)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, event := range pending {
+		retryAt, err := fn(event)
+		m.mu.Lock()
+		if err != nil {
+			m.retry[event.ID] = retryAt
+			for i := range m.events {
+				if m.events[i].ID == event.ID {
+					m.events[i].Attempts++
+				}
+			}
+		} else {
+			m.done[event.ID] = time.Now()
+		}
+		m.mu.Unlock()
+	}
+	return len(pending), nil
+}
+
+func (m *MemoryOutboxRepository) GetLag(ctx context.Context) (*OutboxLag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lag := &OutboxLag{}
+	for _, event := range m.events {
+		if _, done := m.done[event.ID]; done {
+			continue
+		}
+		if lag.Pending == 0 {
+			lag.OldestPendingSeconds = time.Since(event.CreatedAt).Seconds()
+		}
+		lag.Pending++
+	}
+	return lag, nil
+}
+
+func (m *MemoryOutboxRepository) RemoveProcessed(ctx context.Context, before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.events[:0]
+	for _, event := range m.events {
+		if processedAt, done := m.done[event.ID]; done && processedAt.Before(before) {
+			delete(m.done, event.ID)
+			delete(m.retry, event.ID)
+			continue
+		}
+		kept = append(kept, event)
+	}
+	m.events = kept
+	return nil
+}
+
+func (m *MemoryOutboxRepository) GamesChangedSince(ctx context.Context, since time.Time) ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []int
+	for _, event := range m.events {
+		if processedAt, done := m.done[event.ID]; (!done || !processedAt.Before(since)) && !slices.Contains(ids, event.GameID) {
+			ids = append(ids, event.GameID)
+		}
+	}
+	return ids, nil
+}
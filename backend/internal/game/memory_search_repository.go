@@ -0,0 +1,230 @@
+package game
+
+import (
+	"cmp"
+	"context"
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// MemorySearchRepository is a naive SearchRepository over a MemoryRepository: a game
+// matches when its name or description contains every word of the query, ignoring case.
+// Like PostgresSearchRepository it reads the games directly, so indexing is a no-op.
+type MemorySearchRepository struct {
+	games *MemoryRepository
+}
+
+func NewMemorySearchRepository(games *MemoryRepository) *MemorySearchRepository {
+	return &MemorySearchRepository{games: games}
+}
+
+func (m *MemorySearchRepository) IndexGame(ctx context.Context, game *Game) error {
+	return nil
+}
+
+func (m *MemorySearchRepository) DeleteGame(ctx context.Context, id int) error {
+	return nil
+}
+
+func (m *MemorySearchRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+// memoryScore weighs name matches over description matches, like the name^3 boost
+// of the Elasticsearch query. Zero means no match.
+func memoryScore(game Game, terms []string) float64 {
+	name, description := strings.ToLower(game.Name), strings.ToLower(game.Description)
+	score := 0.0
+	for _, term := range terms {
+		inName, inDescription := strings.Contains(name, term), strings.Contains(description, term)
+		if !inName && !inDescription {
+			return 0
+		}
+		if inName {
+			score += 3
+		}
+		if inDescription {
+			score++
+		}
+	}
+	return score
+}
+
+func memoryFilter(game Game, req SearchRequest) bool {
+	switch {
+	case len(req.Genres) > 0 && !slices.ContainsFunc(game.GenreNames(), func(name string) bool {
+		return slices.Contains(req.Genres, name)
+	}):
+		return false
+	case !hasAll(game.TagNames(), req.Tags):
+		return false
+	case req.MinRating != nil && (game.AvgRating == nil || *game.AvgRating < *req.MinRating):
+		return false
+	case req.MaxRating != nil && (game.AvgRating == nil || *game.AvgRating > *req.MaxRating):
+		return false
+	case req.MinReviews > 0 && game.ReviewsCount < req.MinReviews:
+		return false
+	}
+	return true
+}
+
+// highlight escapes text as HTML, like the html encoder of Elasticsearch, so only
+// the <em> tags it adds are markup.
+func highlight(text string, terms []string) (string, bool) {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	re := regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+	var b strings.Builder
+	last := 0
+	for _, m := range re.FindAllStringIndex(text, -1) {
+		if m[0] == m[1] {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[last:m[0]]))
+		b.WriteString("<em>" + html.EscapeString(text[m[0]:m[1]]) + "</em>")
+		last = m[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String(), last > 0
+}
+
+// memoryFacet orders buckets like a terms aggregation: most matches first.
+func memoryFacet(counts map[string]int, size int) []FacetBucket {
+	buckets := []FacetBucket{}
+	for key, count := range counts {
+		buckets = append(buckets, FacetBucket{Key: key, Count: count})
+	}
+	slices.SortFunc(buckets, func(a, b FacetBucket) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Key, b.Key))
+	})
+	return buckets[:min(len(buckets), size)]
+}
+
+func (m *MemorySearchRepository) SearchGames(ctx context.Context, req SearchRequest) (*SearchResult, error) {
+	terms := strings.Fields(strings.ToLower(req.Query))
+	var matched []SearchHit
+	facets := Facets{Ratings: make([]FacetBucket, len(ratingFacetRanges))}
+	for i, r := range ratingFacetRanges {
+		facets.Ratings[i] = FacetBucket{Key: r.Key}
+	}
+	genreCounts, tagCounts := map[string]int{}, map[string]int{}
+
+	for _, game := range m.games.snapshot() {
+		score := memoryScore(game, terms)
+		if score == 0 {
+			continue
+		}
+		if memoryFilter(game, req.withoutGenres()) {
+			for _, name := range game.GenreNames() {
+				genreCounts[name]++
+			}
+		}
+		if game.AvgRating != nil && memoryFilter(game, req.withoutRating()) {
+			facets.Ratings[min(int(*game.AvgRating/2), len(facets.Ratings)-1)].Count++
+		}
+		if !memoryFilter(game, req) {
+			continue
+		}
+		for _, name := range game.TagNames() {
+			tagCounts[name]++
+		}
+		hit := SearchHit{Game: game}
+		if !req.Sort.Valid() {
+			hit.Score = &score
+		}
+		for field, text := range map[string]string{"name": game.Name, "description": game.Description} {
+			if fragment, ok := highlight(text, terms); ok {
+				if hit.Highlights == nil {
+					hit.Highlights = map[string][]string{}
+				}
+				hit.Highlights[field] = []string{fragment}
+			}
+		}
+		matched = append(matched, hit)
+	}
+
+	facets.Genres = memoryFacet(genreCounts, genreFacetSize)
+	facets.Tags = memoryFacet(tagCounts, tagFacetSize)
+
+	slices.SortFunc(matched, func(a, b SearchHit) int {
+		if spec, ok := sortSpecs[req.Sort]; ok {
+			return compareGames(req.Sort, spec.value(&a.Game), a.Game.ID, spec.value(&b.Game), b.Game.ID)
+		}
+		return cmp.Or(cmp.Compare(*b.Score, *a.Score), cmp.Compare(a.Game.ID, b.Game.ID))
+	})
+
+	result := &SearchResult{Hits: []SearchHit{}, Total: len(matched), Facets: facets}
+	if req.From < len(matched) {
+		result.Hits = append(result.Hits, matched[req.From:min(len(matched), req.From+req.Size)]...)
+	}
+	return result, nil
+}
+
+// SuggestGames matches the start of any word of the name, most reviewed first.
+func (m *MemorySearchRepository) SuggestGames(ctx context.Context, prefix string, size int) ([]Suggestion, error) {
+	prefix = strings.ToLower(prefix)
+	var games []Game
+	for _, game := range m.games.snapshot() {
+		name := strings.ToLower(game.Name)
+		if strings.HasPrefix(name, prefix) || strings.Contains(name, " "+prefix) {
+			games = append(games, game)
+		}
+	}
+	slices.SortStableFunc(games, func(a, b Game) int { return cmp.Compare(b.ReviewsCount, a.ReviewsCount) })
+
+	suggestions := []Suggestion{}
+	for _, game := range games[:min(len(games), size)] {
+		suggestions = append(suggestions, Suggestion{ID: game.ID, Name: game.Name, ImageURL: game.ImageURL})
+	}
+	return suggestions, nil
+}
+
+// SimilarGames puts games sharing the most genres, then tags, first.
+func (m *MemorySearchRepository) SimilarGames(ctx context.Context, id int, size int) ([]Game, error) {
+	target, err := m.games.GetGameByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	shared := func(g Game) (genres, tags int) {
+		for _, name := range g.GenreNames() {
+			if slices.Contains(target.GenreNames(), name) {
+				genres++
+			}
+		}
+		for _, name := range g.TagNames() {
+			if slices.Contains(target.TagNames(), name) {
+				tags++
+			}
+		}
+		return genres, tags
+	}
+	var games []Game
+	for _, game := range m.games.snapshot() {
+		if game.ID != id {
+			games = append(games, game)
+		}
+	}
+	slices.SortStableFunc(games, func(a, b Game) int {
+		aGenres, aTags := shared(a)
+		bGenres, bTags := shared(b)
+		return cmp.Or(cmp.Compare(bGenres, aGenres), cmp.Compare(bTags, aTags))
+	})
+	return append([]Game{}, games[:min(len(games), size)]...), nil
+}
+
+// Reindex has nothing to rebuild, it only reports how many games are searchable.
+func (m *MemorySearchRepository) Reindex(ctx context.Context, repo Repository) (*ReindexResult, error) {
+	result := &ReindexResult{Index: "memory", Removed: []string{}, Took: "0s"}
+	err := repo.StreamGames(ctx, func(Game) error {
+		result.Documents++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,129 @@
+package game
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+	// MaxSearchWindow is the index.max_result_window of Elasticsearch: a search
+	// page cannot end past it, deeper results need a narrower query.
+	MaxSearchWindow = 10000
+)
+
+type SortOrder string
+
+const (
+	SortByName    SortOrder = "name"
+	SortByRating  SortOrder = "avg_rating"
+	SortByReviews SortOrder = "reviews_count"
+	SortByNewest  SortOrder = "newest"
+)
+
+func (s SortOrder) Valid() bool {
+	switch s {
+	case SortByName, SortByRating, SortByReviews, SortByNewest:
+		return true
+	}
+	return false
+}
+
+// ListOptions is shared by the handler, service and repository when listing the catalog.
+// Zero values mean "no filter".
+type ListOptions struct {
+	GenreID    int
+	GenreName  string
+	Tags       []string // a game matches if it has all of them
+	MinRating  *float64
+	MinReviews int
+	Sort       SortOrder
+	Limit      int
+	After      *Cursor
+}
+
+// Cursor points at the last game of a page; the next page starts right after it.
+type Cursor struct {
+	Sort  SortOrder `json:"s"`
+	Value string    `json:"v"`
+	ID    int       `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || !c.Sort.Valid() || c.ID <= 0 {
+		return nil, errors.New("invalid cursor")
+	}
+	return &c, nil
+}
+
+type Page struct {
+	Games      []Game `json:"games"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// SearchRequest is a full-text search over the catalog. An empty Sort orders by relevance.
+// Zero-valued filters are not applied.
+type SearchRequest struct {
+	Query      string
+	Genres     []string // a game matches if it has any of them
+	Tags       []string // a game matches if it has all of them
+	MinRating  *float64
+	MaxRating  *float64
+	MinReviews int
+	Sort       SortOrder
+	From       int
+	Size       int
+}
+
+type FacetBucket struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// Facets count matches per filter value. The genre and rating facets ignore their own
+// filter, so the sidebar keeps showing the alternatives to what is selected. Tags must
+// all match, so the tag facet counts the tags that can still narrow the results.
+type Facets struct {
+	Genres  []FacetBucket `json:"genres"`
+	Tags    []FacetBucket `json:"tags"`
+	Ratings []FacetBucket `json:"ratings"`
+}
+
+// withoutGenres is r for the genre facet.
+func (r SearchRequest) withoutGenres() SearchRequest {
+	r.Genres = nil
+	return r
+}
+
+// withoutRating is r for the rating facet.
+func (r SearchRequest) withoutRating() SearchRequest {
+	r.MinRating, r.MaxRating = nil, nil
+	return r
+}
+
+// SearchHit is a matched game with why it matched. Score is nil when results
+// are sorted by a field instead of relevance.
+type SearchHit struct {
+	Game       Game                `json:"game"`
+	Score      *float64            `json:"score"`
+	Highlights map[string][]string `json:"highlights,omitempty"`
+}
+
+type SearchResult struct {
+	Hits   []SearchHit `json:"hits"`
+	Total  int         `json:"total"`
+	Facets Facets      `json:"facets"`
+}
@@ -0,0 +1,159 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"igropoisk_backend/internal/logger"
+	"sync"
+	"time"
+)
+
+type OutboxAction string
+
+const (
+	OutboxIndex  OutboxAction = "index"
+	OutboxDelete OutboxAction = "delete"
+)
+
+// OutboxEvent records that a game changed and the search index has to catch up.
+type OutboxEvent struct {
+	ID        int64
+	GameID    int
+	Action    OutboxAction
+	Attempts  int
+	CreatedAt time.Time
+}
+
+type OutboxLag struct {
+	Pending              int        `json:"pending"`
+	OldestPendingSeconds float64    `json:"oldest_pending_seconds"`
+	LastSuccessAt        *time.Time `json:"last_success_at"`
+	LastError            string     `json:"last_error,omitempty"`
+}
+
+const (
+	outboxPollInterval = time.Second
+	outboxBatchSize    = 100
+	outboxBaseBackoff  = time.Second
+	outboxMaxBackoff   = 5 * time.Minute
+	outboxRetention    = 24 * time.Hour
+	// outboxClaimTimeout bounds how long a claimed batch may take before other dispatchers retry it.
+	outboxClaimTimeout = 5 * time.Minute
+)
+
+// OutboxDispatcher applies outbox events to the search index in the background.
+type OutboxDispatcher struct {
+	outbox     OutboxRepository
+	gameRepo   Repository
+	searchRepo SearchRepository
+
+	mu            sync.Mutex
+	lastSuccessAt *time.Time
+	lastError     string
+}
+
+func NewOutboxDispatcher(outbox OutboxRepository, gameRepo Repository, searchRepo SearchRepository) *OutboxDispatcher {
+	return &OutboxDispatcher{outbox: outbox, gameRepo: gameRepo, searchRepo: searchRepo}
+}
+
+// Run polls the outbox until ctx is cancelled.
+func (d *OutboxDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+	cleanup := time.NewTicker(time.Hour)
+	defer cleanup.Stop()
+
+	apply := func(event OutboxEvent) (time.Time, error) {
+		return d.apply(ctx, event)
+	}
+	for {
+		// drain full batches right away instead of waiting for the next tick
+		for {
+			n, err := d.outbox.ProcessPending(ctx, outboxBatchSize, apply)
+			if err != nil && ctx.Err() == nil {
+				logger.Logger.Error("Failed to process search outbox",
+					"error", err)
+			}
+			if err != nil || n < outboxBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-cleanup.C:
+			if err := d.outbox.RemoveProcessed(ctx, time.Now().Add(-outboxRetention)); err != nil {
+				logger.Logger.Warn("Failed to clean up search outbox",
+					"error", err)
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// apply re-reads the game so that retries and out-of-order events always
+// leave the index matching the current row.
+func (d *OutboxDispatcher) apply(ctx context.Context, event OutboxEvent) (time.Time, error) {
+	err := d.sync(ctx, event)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err != nil {
+		d.lastError = err.Error()
+		retryAt := time.Now().Add(outboxBackoff(event.Attempts))
+		logger.Logger.Warn("Failed to apply search outbox event",
+			"event_id", event.ID,
+			"game_id", event.GameID,
+			"action", event.Action,
+			"attempts", event.Attempts+1,
+			"retry_at", retryAt,
+			"error", err)
+		return retryAt, err
+	}
+	now := time.Now()
+	d.lastSuccessAt = &now
+	return time.Time{}, nil
+}
+
+func (d *OutboxDispatcher) sync(ctx context.Context, event OutboxEvent) error {
+	if event.Action == OutboxDelete {
+		return d.searchRepo.DeleteGame(ctx, event.GameID)
+	}
+	return syncGame(ctx, d.gameRepo, d.searchRepo, event.GameID)
+}
+
+// syncGame indexes the current row of a game, or removes it from the index when it is gone.
+func syncGame(ctx context.Context, gameRepo Repository, searchRepo SearchRepository, id int) error {
+	game, err := gameRepo.GetGameByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return searchRepo.DeleteGame(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+	if game.ReviewsText, err = gameRepo.GetReviewsText(ctx, id); err != nil {
+		return err
+	}
+	return searchRepo.IndexGame(ctx, game)
+}
+
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxBaseBackoff
+	for i := 0; i < attempts && backoff < outboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, outboxMaxBackoff)
+}
+
+func (d *OutboxDispatcher) Lag(ctx context.Context) (*OutboxLag, error) {
+	lag, err := d.outbox.GetLag(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	lag.LastSuccessAt = d.lastSuccessAt
+	lag.LastError = d.lastError
+	d.mu.Unlock()
+	return lag, nil
+}
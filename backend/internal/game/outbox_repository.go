@@ -0,0 +1,153 @@
+package game
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+//go:embed queries/add_outbox_event.sql
+var addOutboxEventSQL string
+
+//go:embed queries/add_outbox_events.sql
+var addOutboxEventsSQL string
+
+//go:embed queries/claim_outbox_events.sql
+var claimOutboxEventsSQL string
+
+//go:embed queries/mark_outbox_event_processed.sql
+var markOutboxEventProcessedSQL string
+
+//go:embed queries/mark_outbox_event_failed.sql
+var markOutboxEventFailedSQL string
+
+//go:embed queries/get_outbox_lag.sql
+var getOutboxLagSQL string
+
+//go:embed queries/remove_processed_outbox_events.sql
+var removeProcessedOutboxEventsSQL string
+
+//go:embed queries/get_games_changed_since.sql
+var getGamesChangedSinceSQL string
+
+type OutboxRepository interface {
+	// ProcessPending claims up to limit due events for outboxClaimTimeout, so concurrent
+	// dispatchers skip them, and hands each one to fn outside of any transaction. A nil
+	// result marks the event processed, an error schedules a retry; events of a dispatcher
+	// that dies before marking them are due again once the claim runs out.
+	ProcessPending(ctx context.Context, limit int, fn func(OutboxEvent) (retryAt time.Time, err error)) (int, error)
+	GetLag(ctx context.Context) (*OutboxLag, error)
+	RemoveProcessed(ctx context.Context, before time.Time) error
+	// GamesChangedSince lists the games of pending events and of events processed at
+	// or after since, for a Reindex to replay the writes it raced with.
+	GamesChangedSince(ctx context.Context, since time.Time) ([]int, error)
+}
+
+type PostgresOutboxRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresOutboxRepository(pool *pgxpool.Pool) OutboxRepository {
+	return &PostgresOutboxRepository{pool: pool}
+}
+
+// addOutboxEvent must run in the transaction that changes the game.
+func addOutboxEvent(ctx context.Context, tx pgx.Tx, gameID int, action OutboxAction) error {
+	_, err := tx.Exec(ctx, addOutboxEventSQL, gameID, action)
+	if err != nil {
+		return fmt.Errorf("addOutboxEvent: %w", err)
+	}
+	return nil
+}
+
+// addOutboxEvents records the same action for many games, see addOutboxEvent.
+func addOutboxEvents(ctx context.Context, tx pgx.Tx, gameIDs []int, action OutboxAction) error {
+	_, err := tx.Exec(ctx, addOutboxEventsSQL, gameIDs, action)
+	if err != nil {
+		return fmt.Errorf("addOutboxEvents: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresOutboxRepository) ProcessPending(ctx context.Context, limit int, fn func(OutboxEvent) (time.Time, error)) (int, error) {
+	// the claim commits on its own, no connection or row lock is held while fn calls the index
+	rows, err := p.pool.Query(ctx, claimOutboxEventsSQL, limit, time.Now().Add(outboxClaimTimeout))
+	if err != nil {
+		return 0, fmt.Errorf("ProcessPending claim: %w", err)
+	}
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (OutboxEvent, error) {
+		var e OutboxEvent
+		err := row.Scan(&e.ID, &e.GameID, &e.Action, &e.Attempts, &e.CreatedAt)
+		return e, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ProcessPending Scan: %w", err)
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	type outcome struct {
+		retryAt time.Time
+		err     error
+	}
+	outcomes := make([]outcome, len(events))
+	for i, event := range events {
+		outcomes[i].retryAt, outcomes[i].err = fn(event)
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("ProcessPending begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	for i, event := range events {
+		if o := outcomes[i]; o.err != nil {
+			_, err = tx.Exec(ctx, markOutboxEventFailedSQL, event.ID, o.err.Error(), o.retryAt)
+		} else {
+			_, err = tx.Exec(ctx, markOutboxEventProcessedSQL, event.ID)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("ProcessPending mark: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("ProcessPending commit: %w", err)
+	}
+	return len(events), nil
+}
+
+func (p *PostgresOutboxRepository) GetLag(ctx context.Context) (*OutboxLag, error) {
+	lag := &OutboxLag{}
+	var oldest *time.Time
+	if err := p.pool.QueryRow(ctx, getOutboxLagSQL).Scan(&lag.Pending, &oldest); err != nil {
+		return nil, fmt.Errorf("GetLag: %w", err)
+	}
+	if oldest != nil {
+		lag.OldestPendingSeconds = time.Since(*oldest).Seconds()
+	}
+	return lag, nil
+}
+
+func (p *PostgresOutboxRepository) RemoveProcessed(ctx context.Context, before time.Time) error {
+	_, err := p.pool.Exec(ctx, removeProcessedOutboxEventsSQL, before)
+	if err != nil {
+		return fmt.Errorf("RemoveProcessed: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresOutboxRepository) GamesChangedSince(ctx context.Context, since time.Time) ([]int, error) {
+	rows, err := p.pool.Query(ctx, getGamesChangedSinceSQL, since)
+	if err != nil {
+		return nil, fmt.Errorf("GamesChangedSince: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, fmt.Errorf("GamesChangedSince: %w", err)
+	}
+	return ids, nil
+}
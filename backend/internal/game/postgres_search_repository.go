@@ -0,0 +1,258 @@
+package game
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"strconv"
+	"strings"
+)
+
+//go:embed queries/search_games.sql
+var searchGamesSQL string
+
+//go:embed queries/search_count.sql
+var searchCountSQL string
+
+//go:embed queries/search_genre_facet.sql
+var searchGenreFacetSQL string
+
+//go:embed queries/search_tag_facet.sql
+var searchTagFacetSQL string
+
+//go:embed queries/search_rating_facet.sql
+var searchRatingFacetSQL string
+
+//go:embed queries/suggest_games.sql
+var suggestGamesSQL string
+
+//go:embed queries/get_similar_games.sql
+var getSimilarGamesSQL string
+
+// PostgresSearchRepository searches the games table through its generated search_vector
+// column and pg_trgm, for environments without Elasticsearch. The column is kept
+// up to date by Postgres, so indexing is a no-op.
+type PostgresSearchRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresSearchRepository(pool *pgxpool.Pool) SearchRepository {
+	return &PostgresSearchRepository{pool: pool}
+}
+
+func (p *PostgresSearchRepository) IndexGame(ctx context.Context, game *Game) error {
+	return nil
+}
+
+func (p *PostgresSearchRepository) DeleteGame(ctx context.Context, id int) error {
+	return nil
+}
+
+func (p *PostgresSearchRepository) Ping(ctx context.Context) error {
+	return p.pool.Ping(ctx)
+}
+
+// pgSearchSorts mirror searchSorts; relevance is the default.
+var pgSearchSorts = map[SortOrder]string{
+	SortByName:    "game.name ASC, game.id",
+	SortByRating:  "game.avg_rating DESC NULLS LAST, game.id",
+	SortByReviews: "game.reviews_count DESC, game.id",
+	SortByNewest:  "game.id DESC",
+}
+
+// searchFilters renders the filters of req after the query text, which is always $1.
+func searchFilters(req SearchRequest) (conds []string, args []any) {
+	args = []any{req.Query}
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if len(req.Genres) > 0 {
+		conds = append(conds, hasAnyGenre(arg(req.Genres)))
+	}
+	if len(req.Tags) > 0 {
+		conds = append(conds, hasAllTags(arg(req.Tags), len(req.Tags)))
+	}
+	if req.MinRating != nil {
+		conds = append(conds, "game.avg_rating >= "+arg(*req.MinRating))
+	}
+	if req.MaxRating != nil {
+		conds = append(conds, "game.avg_rating <= "+arg(*req.MaxRating))
+	}
+	if req.MinReviews > 0 {
+		conds = append(conds, "game.reviews_count >= "+arg(req.MinReviews))
+	}
+	return conds, args
+}
+
+func withConds(query string, conds []string) string {
+	for _, cond := range conds {
+		query += " AND " + cond
+	}
+	return query
+}
+
+func (p *PostgresSearchRepository) SearchGames(ctx context.Context, req SearchRequest) (*SearchResult, error) {
+	conds, args := searchFilters(req)
+	order, ok := pgSearchSorts[req.Sort]
+	if !ok {
+		order = "score DESC, game.id"
+	}
+	query := withConds(searchGamesSQL, conds) +
+		fmt.Sprintf(" ORDER BY %s LIMIT $%d OFFSET $%d", order, len(args)+1, len(args)+2)
+
+	rows, err := p.pool.Query(ctx, query, append(args, req.Size, req.From)...)
+	if err != nil {
+		return nil, fmt.Errorf("SearchGames: %w", err)
+	}
+	defer rows.Close()
+
+	result := &SearchResult{Hits: []SearchHit{}}
+	for rows.Next() {
+		var hit SearchHit
+		var score float64
+		var nameHighlight, descriptionHighlight string
+		dest := append(gameColumns(&hit.Game), &score, &nameHighlight, &descriptionHighlight, &result.Total)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("SearchGames Scan: %w", err)
+		}
+		if !ok {
+			hit.Score = &score
+		}
+		// ts_headline returns the text as is when nothing matched
+		for field, fragment := range map[string]string{"name": nameHighlight, "description": descriptionHighlight} {
+			if strings.Contains(fragment, "<em>") {
+				if hit.Highlights == nil {
+					hit.Highlights = map[string][]string{}
+				}
+				hit.Highlights[field] = []string{fragment}
+			}
+		}
+		result.Hits = append(result.Hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("SearchGames rows: %w", err)
+	}
+	// the total comes with the rows, a page past the last match has to count them
+	if len(result.Hits) == 0 && req.From > 0 {
+		if err := p.pool.QueryRow(ctx, withConds(searchCountSQL, conds), args...).Scan(&result.Total); err != nil {
+			return nil, fmt.Errorf("SearchGames count: %w", err)
+		}
+	}
+
+	if result.Facets, err = p.facets(ctx, req); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// facets follows the Elasticsearch semantics: the genre and rating facets ignore their own filter.
+func (p *PostgresSearchRepository) facets(ctx context.Context, req SearchRequest) (Facets, error) {
+	facets := Facets{Ratings: make([]FacetBucket, len(ratingFacetRanges))}
+
+	var err error
+	conds, args := searchFilters(req.withoutGenres())
+	facets.Genres, err = p.termsFacet(ctx, withConds(searchGenreFacetSQL, conds)+
+		fmt.Sprintf(" GROUP BY ge.name ORDER BY COUNT(*) DESC, ge.name LIMIT %d", genreFacetSize), args)
+	if err != nil {
+		return facets, fmt.Errorf("SearchGames genre facet: %w", err)
+	}
+	conds, args = searchFilters(req)
+	facets.Tags, err = p.termsFacet(ctx, withConds(searchTagFacetSQL, conds)+
+		fmt.Sprintf(" GROUP BY t.name ORDER BY COUNT(*) DESC, t.name LIMIT %d", tagFacetSize), args)
+	if err != nil {
+		return facets, fmt.Errorf("SearchGames tag facet: %w", err)
+	}
+
+	for i, r := range ratingFacetRanges {
+		facets.Ratings[i] = FacetBucket{Key: r.Key}
+	}
+	conds, args = searchFilters(req.withoutRating())
+	rows, err := p.pool.Query(ctx, withConds(searchRatingFacetSQL, conds)+" GROUP BY bucket", args...)
+	if err != nil {
+		return facets, fmt.Errorf("SearchGames rating facet: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bucket, count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return facets, fmt.Errorf("SearchGames rating facet Scan: %w", err)
+		}
+		if bucket >= 0 && bucket < len(facets.Ratings) {
+			facets.Ratings[bucket].Count = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return facets, fmt.Errorf("SearchGames rating facet rows: %w", err)
+	}
+	return facets, nil
+}
+
+// termsFacet reads (key, count) rows.
+func (p *PostgresSearchRepository) termsFacet(ctx context.Context, query string, args []any) ([]FacetBucket, error) {
+	rows, err := p.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	buckets, err := pgx.CollectRows(rows, pgx.RowToStructByPos[FacetBucket])
+	if err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (p *PostgresSearchRepository) SuggestGames(ctx context.Context, prefix string, size int) ([]Suggestion, error) {
+	rows, err := p.pool.Query(ctx, suggestGamesSQL, likeEscaper.Replace(prefix), size)
+	if err != nil {
+		return nil, fmt.Errorf("SuggestGames: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions := []Suggestion{}
+	for rows.Next() {
+		var s Suggestion
+		if err := rows.Scan(&s.ID, &s.Name, &s.ImageURL); err != nil {
+			return nil, fmt.Errorf("SuggestGames Scan: %w", err)
+		}
+		suggestions = append(suggestions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("SuggestGames rows: %w", err)
+	}
+	return suggestions, nil
+}
+
+func (p *PostgresSearchRepository) SimilarGames(ctx context.Context, id int, size int) ([]Game, error) {
+	rows, err := p.pool.Query(ctx, getSimilarGamesSQL, id, size)
+	if err != nil {
+		return nil, fmt.Errorf("SimilarGames: %w", err)
+	}
+	defer rows.Close()
+
+	games := []Game{}
+	for rows.Next() {
+		var game Game
+		if err := rows.Scan(gameColumns(&game)...); err != nil {
+			return nil, fmt.Errorf("SimilarGames Scan: %w", err)
+		}
+		games = append(games, game)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("SimilarGames rows: %w", err)
+	}
+	return games, nil
+}
+
+// Reindex has nothing to rebuild, it only reports how many games are searchable.
+func (p *PostgresSearchRepository) Reindex(ctx context.Context, repo Repository) (*ReindexResult, error) {
+	result := &ReindexResult{Index: "postgres", Removed: []string{}}
+	if err := p.pool.QueryRow(ctx, countGamesSQL).Scan(&result.Documents); err != nil {
+		return nil, fmt.Errorf("Reindex: %w", err)
+	}
+	result.Took = "0s"
+	return result, nil
+}
@@ -1 +1 @@
-INSERT INTO games (name,description,image_url,genre_id) VALUES ($1,$2,$3,$4) RETURNING id;
+INSERT INTO games (name,description,image_url) VALUES ($1,$2,$3) RETURNING id;
@@ -0,0 +1 @@
+INSERT INTO game_genres (game_id, genre_id) SELECT * FROM unnest($1::int[], $2::int[])
\ No newline at end of file
@@ -0,0 +1 @@
+INSERT INTO game_tags (game_id, tag_id) SELECT * FROM unnest($1::int[], $2::int[])
\ No newline at end of file
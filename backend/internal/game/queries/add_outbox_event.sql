@@ -0,0 +1 @@
+INSERT INTO search_outbox (game_id, action) VALUES ($1, $2)
\ No newline at end of file
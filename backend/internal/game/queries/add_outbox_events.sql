@@ -0,0 +1 @@
+INSERT INTO search_outbox (game_id, action) SELECT unnest($1::int[]), $2
\ No newline at end of file
@@ -0,0 +1,12 @@
+WITH claimed AS (
+    UPDATE search_outbox
+    SET next_attempt_at = $2
+    WHERE id IN (SELECT id
+                 FROM search_outbox
+                 WHERE processed_at IS NULL AND next_attempt_at <= now()
+                 ORDER BY id
+                 LIMIT $1
+                 FOR UPDATE SKIP LOCKED)
+    RETURNING id, game_id, action, attempts, created_at
+)
+SELECT id, game_id, action, attempts, created_at FROM claimed ORDER BY id
\ No newline at end of file
@@ -0,0 +1,2 @@
+SELECT COUNT(*)
+FROM games game
\ No newline at end of file
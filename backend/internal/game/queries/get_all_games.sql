@@ -5,7 +5,6 @@ SELECT
     game.reviews_count,
     game.description,
     game.image_url,
-    ge.id AS genre_id,
-    ge.name AS genre_name
-FROM games game
-         JOIN genres ge ON game.genre_id = ge.id
\ No newline at end of file
+    game.genres,
+    game.tags
+FROM game_details game
\ No newline at end of file
@@ -0,0 +1,18 @@
+SELECT
+    game.id,
+    game.name,
+    game.avg_rating,
+    game.reviews_count,
+    game.description,
+    game.image_url,
+    game.genres,
+    game.tags,
+    reviews_text.text
+FROM game_details game,
+     LATERAL (SELECT coalesce(string_agg(review.description, E'\n'), '') AS text
+              FROM (SELECT description
+                    FROM reviews
+                    WHERE game_id = game.id AND description <> ''
+                    ORDER BY id DESC
+                    LIMIT 50) review) reviews_text
+ORDER BY game.id
@@ -1 +1,11 @@
-SELECT id,name,avg_rating,reviews_count,description,image_url,genre_id FROM games WHERE id = $1;
+SELECT
+    game.id,
+    game.name,
+    game.avg_rating,
+    game.reviews_count,
+    game.description,
+    game.image_url,
+    game.genres,
+    game.tags
+FROM game_details game
+WHERE game.id = $1;
@@ -1 +1,11 @@
-SELECT id,name,avg_rating,reviews_count,description,image_url,genre_id FROM games WHERE name = $1;
\ No newline at end of file
+SELECT
+    game.id,
+    game.name,
+    game.avg_rating,
+    game.reviews_count,
+    game.description,
+    game.image_url,
+    game.genres,
+    game.tags
+FROM game_details game
+WHERE game.name = $1;
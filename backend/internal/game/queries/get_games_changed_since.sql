@@ -0,0 +1 @@
+SELECT DISTINCT game_id FROM search_outbox WHERE processed_at IS NULL OR processed_at >= $1
\ No newline at end of file
@@ -0,0 +1 @@
+SELECT COUNT(*), MIN(created_at) FROM search_outbox WHERE processed_at IS NULL
\ No newline at end of file
@@ -0,0 +1,6 @@
+SELECT coalesce(string_agg(review.description, E'\n'), '')
+FROM (SELECT description
+      FROM reviews
+      WHERE game_id = $1 AND description <> ''
+      ORDER BY id DESC
+      LIMIT 50) review
@@ -0,0 +1,23 @@
+SELECT
+    game.id,
+    game.name,
+    game.avg_rating,
+    game.reviews_count,
+    game.description,
+    game.image_url,
+    game.genres,
+    game.tags
+FROM games target
+         JOIN game_details game ON game.id <> target.id
+WHERE target.id = $1
+ORDER BY (SELECT COUNT(*)
+          FROM game_genres a
+                   JOIN game_genres b ON b.genre_id = a.genre_id
+          WHERE a.game_id = target.id AND b.game_id = game.id) DESC,
+         (SELECT COUNT(*)
+          FROM game_tags a
+                   JOIN game_tags b ON b.tag_id = a.tag_id
+          WHERE a.game_id = target.id AND b.game_id = game.id) DESC,
+         similarity(coalesce(game.description, ''), coalesce(target.description, '')) DESC,
+         game.id
+LIMIT $2
\ No newline at end of file
@@ -0,0 +1,4 @@
+INSERT INTO games (name, description, image_url)
+SELECT * FROM unnest($1::text[], $2::text[], $3::text[])
+ON CONFLICT (name) DO NOTHING
+RETURNING id, name
@@ -0,0 +1 @@
+UPDATE search_outbox SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3 WHERE id = $1
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE search_outbox SET processed_at = clock_timestamp(), last_error = NULL WHERE id = $1
\ No newline at end of file
@@ -0,0 +1 @@
+DELETE FROM game_genres WHERE game_id = $1
\ No newline at end of file
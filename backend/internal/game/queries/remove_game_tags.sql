@@ -0,0 +1 @@
+DELETE FROM game_tags WHERE game_id = $1
\ No newline at end of file
@@ -0,0 +1 @@
+DELETE FROM search_outbox WHERE processed_at < $1
\ No newline at end of file
@@ -0,0 +1,4 @@
+SELECT COUNT(*)
+FROM games game,
+     LATERAL (SELECT websearch_to_tsquery('russian', $1) AS ru, websearch_to_tsquery('english', $1) AS en) q
+WHERE (game.search_vector @@ (q.ru || q.en) OR game.name % $1)
\ No newline at end of file
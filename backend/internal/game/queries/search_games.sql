@@ -0,0 +1,16 @@
+SELECT
+    game.id,
+    game.name,
+    game.avg_rating,
+    game.reviews_count,
+    game.description,
+    game.image_url,
+    game.genres,
+    game.tags,
+    ts_rank(game.search_vector, q.ru || q.en) + similarity(game.name, $1) AS score,
+    ts_headline('russian', html_escape(game.name), q.ru || q.en, 'StartSel=<em>, StopSel=</em>, HighlightAll=true') AS name_highlight,
+    ts_headline('russian', html_escape(coalesce(game.description, '')), q.ru || q.en, 'StartSel=<em>, StopSel=</em>, MaxFragments=3') AS description_highlight,
+    COUNT(*) OVER () AS total
+FROM game_details game,
+     LATERAL (SELECT websearch_to_tsquery('russian', $1) AS ru, websearch_to_tsquery('english', $1) AS en) q
+WHERE (game.search_vector @@ (q.ru || q.en) OR game.name % $1)
\ No newline at end of file
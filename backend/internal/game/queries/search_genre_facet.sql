@@ -0,0 +1,6 @@
+SELECT ge.name, COUNT(*)
+FROM games game
+         JOIN game_genres gg ON gg.game_id = game.id
+         JOIN genres ge ON ge.id = gg.genre_id,
+     LATERAL (SELECT websearch_to_tsquery('russian', $1) AS ru, websearch_to_tsquery('english', $1) AS en) q
+WHERE (game.search_vector @@ (q.ru || q.en) OR game.name % $1)
\ No newline at end of file
@@ -0,0 +1,5 @@
+SELECT LEAST(FLOOR(game.avg_rating / 2), 4)::int AS bucket, COUNT(*)
+FROM games game,
+     LATERAL (SELECT websearch_to_tsquery('russian', $1) AS ru, websearch_to_tsquery('english', $1) AS en) q
+WHERE (game.search_vector @@ (q.ru || q.en) OR game.name % $1)
+  AND game.avg_rating IS NOT NULL
\ No newline at end of file
@@ -0,0 +1,6 @@
+SELECT t.name, COUNT(*)
+FROM games game
+         JOIN game_tags gt ON gt.game_id = game.id
+         JOIN tags t ON t.id = gt.tag_id,
+     LATERAL (SELECT websearch_to_tsquery('russian', $1) AS ru, websearch_to_tsquery('english', $1) AS en) q
+WHERE (game.search_vector @@ (q.ru || q.en) OR game.name % $1)
\ No newline at end of file
@@ -0,0 +1,5 @@
+SELECT id, name, image_url
+FROM games
+WHERE name ILIKE $1 || '%' OR name ILIKE '% ' || $1 || '%'
+ORDER BY reviews_count DESC, id
+LIMIT $2
\ No newline at end of file
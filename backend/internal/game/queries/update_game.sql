@@ -0,0 +1 @@
+UPDATE games SET name = $2, description = $3, image_url = $4 WHERE id = $1;
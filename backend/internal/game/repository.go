@@ -3,9 +3,16 @@ package game
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"igropoisk_backend/internal/game/genre"
+	"igropoisk_backend/internal/game/tag"
+	"slices"
+	"strconv"
+	"strings"
 )
 
 //go:embed queries/add_game.sql
@@ -23,12 +30,48 @@ var getGameByNameSQL string
 //go:embed queries/get_all_games.sql
 var getAllGamesSQL string
 
+//go:embed queries/update_game.sql
+var updateGameSQL string
+
+//go:embed queries/get_all_games_ordered.sql
+var getAllGamesOrderedSQL string
+
+//go:embed queries/count_games.sql
+var countGamesSQL string
+
+//go:embed queries/get_reviews_text.sql
+var getReviewsTextSQL string
+
+//go:embed queries/import_games.sql
+var importGamesSQL string
+
+//go:embed queries/add_game_genres.sql
+var addGameGenresSQL string
+
+//go:embed queries/remove_game_genres.sql
+var removeGameGenresSQL string
+
+//go:embed queries/add_game_tags.sql
+var addGameTagsSQL string
+
+//go:embed queries/remove_game_tags.sql
+var removeGameTagsSQL string
+
 type Repository interface {
 	AddGame(ctx context.Context, game *Game) error
+	UpdateGame(ctx context.Context, game *Game) error
 	RemoveGameByID(ctx context.Context, id int) error
 	GetGameByID(ctx context.Context, id int) (*Game, error)
-	GetAllGames(ctx context.Context) ([]Game, error)
+	GetAllGames(ctx context.Context, opts ListOptions) (*Page, error)
+	// StreamGames calls fn for every game in id order without loading the catalog into memory.
+	StreamGames(ctx context.Context, fn func(Game) error) error
 	GetGameByName(ctx context.Context, name string) (*Game, error)
+	// GetReviewsText joins the latest review texts of a game, which similar games are matched on.
+	GetReviewsText(ctx context.Context, id int) (string, error)
+	// ImportGames adds games in one transaction, skipping names that are already taken.
+	// Added games get their ID set, skipped ones keep 0. Names must be distinct.
+	// Genres and tags are given by name; missing ones are created in the same transaction.
+	ImportGames(ctx context.Context, games []Game) error
 }
 
 type PostgresRepository struct {
@@ -39,19 +82,23 @@ func NewPostgresRepository(pool *pgxpool.Pool) Repository {
 	return &PostgresRepository{pool: pool}
 }
 
-func (p *PostgresRepository) GetGameByID(ctx context.Context, id int) (*Game, error) {
-	game := &Game{}
-	genre := &genre.Genre{}
-	err := p.pool.QueryRow(ctx, getGameByIDSQL, id).Scan(
+// gameColumns are the scan targets of the game columns every read query starts with.
+func gameColumns(game *Game) []any {
+	return []any{
 		&game.ID,
 		&game.Name,
 		&game.AvgRating,
 		&game.ReviewsCount,
 		&game.Description,
 		&game.ImageURL,
-		&genre.ID,
-	)
-	if err != nil {
+		&game.Genres,
+		&game.Tags,
+	}
+}
+
+func (p *PostgresRepository) GetGameByID(ctx context.Context, id int) (*Game, error) {
+	game := &Game{}
+	if err := p.pool.QueryRow(ctx, getGameByIDSQL, id).Scan(gameColumns(game)...); err != nil {
 		return nil, fmt.Errorf("GetGameByID: %w", err)
 	}
 	return game, nil
@@ -59,66 +106,366 @@ func (p *PostgresRepository) GetGameByID(ctx context.Context, id int) (*Game, er
 
 func (p *PostgresRepository) GetGameByName(ctx context.Context, name string) (*Game, error) {
 	game := &Game{}
-	genre := &genre.Genre{}
-	err := p.pool.QueryRow(ctx, getGameByNameSQL, name).Scan(
-		&game.ID,
-		&game.Name,
-		&game.AvgRating,
-		&game.ReviewsCount,
-		&game.Description,
-		&game.ImageURL,
-		&genre.ID,
-	)
-	game.Genre = *genre
-	if err != nil {
+	if err := p.pool.QueryRow(ctx, getGameByNameSQL, name).Scan(gameColumns(game)...); err != nil {
 		return nil, fmt.Errorf("GetGameByName: %w", err)
 	}
 	return game, nil
 }
 
-func (p *PostgresRepository) GetAllGames(ctx context.Context) ([]Game, error) {
-	rows, err := p.pool.Query(ctx, getAllGamesSQL)
+func (p *PostgresRepository) GetReviewsText(ctx context.Context, id int) (string, error) {
+	var text string
+	if err := p.pool.QueryRow(ctx, getReviewsTextSQL, id).Scan(&text); err != nil {
+		return "", fmt.Errorf("GetReviewsText: %w", err)
+	}
+	return text, nil
+}
+
+type sortSpec struct {
+	key   string // sql expression the page is ordered by
+	cast  string
+	desc  bool
+	value func(game *Game) string // the game's key, as stored in a cursor
+}
+
+// games without enough reviews have no rating and go last when sorting by it
+var sortSpecs = map[SortOrder]sortSpec{
+	SortByName: {key: "game.name", cast: "text", value: func(g *Game) string {
+		return g.Name
+	}},
+	SortByRating: {key: "COALESCE(game.avg_rating, -1)", cast: "numeric", desc: true, value: func(g *Game) string {
+		if g.AvgRating == nil {
+			return "-1"
+		}
+		return strconv.FormatFloat(*g.AvgRating, 'f', -1, 64)
+	}},
+	SortByReviews: {key: "game.reviews_count", cast: "int", desc: true, value: func(g *Game) string {
+		return strconv.Itoa(g.ReviewsCount)
+	}},
+	SortByNewest: {key: "game.id", cast: "int", desc: true, value: func(g *Game) string {
+		return strconv.Itoa(g.ID)
+	}},
+}
+
+// hasGenreID, hasAnyGenre and hasAllTags are conditions on the game aliased "game".
+func hasGenreID(arg string) string {
+	return "EXISTS (SELECT 1 FROM game_genres gg WHERE gg.game_id = game.id AND gg.genre_id = " + arg + ")"
+}
+
+func hasAnyGenre(arg string) string {
+	return "EXISTS (SELECT 1 FROM game_genres gg JOIN genres ge ON ge.id = gg.genre_id" +
+		" WHERE gg.game_id = game.id AND ge.name = ANY(" + arg + "))"
+}
+
+// hasAllTags expects distinct tag names.
+func hasAllTags(arg string, count int) string {
+	return fmt.Sprintf("(SELECT COUNT(*) FROM game_tags gt JOIN tags t ON t.id = gt.tag_id"+
+		" WHERE gt.game_id = game.id AND t.name = ANY(%s)) = %d", arg, count)
+}
+
+func buildGameFilters(opts ListOptions) (conds []string, args []any) {
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if opts.GenreID > 0 {
+		conds = append(conds, hasGenreID(arg(opts.GenreID)))
+	}
+	if opts.GenreName != "" {
+		conds = append(conds, hasAnyGenre(arg([]string{opts.GenreName})))
+	}
+	if len(opts.Tags) > 0 {
+		conds = append(conds, hasAllTags(arg(opts.Tags), len(opts.Tags)))
+	}
+	if opts.MinRating != nil {
+		conds = append(conds, "game.avg_rating >= "+arg(*opts.MinRating))
+	}
+	if opts.MinReviews > 0 {
+		conds = append(conds, "game.reviews_count >= "+arg(opts.MinReviews))
+	}
+	return conds, args
+}
+
+func (p *PostgresRepository) GetAllGames(ctx context.Context, opts ListOptions) (*Page, error) {
+	spec, ok := sortSpecs[opts.Sort]
+	if !ok {
+		return nil, fmt.Errorf("GetAllGames: unknown sort order %q", opts.Sort)
+	}
+	conds, args := buildGameFilters(opts)
+
+	page := &Page{Games: []Game{}}
+	countSQL := countGamesSQL
+	if len(conds) > 0 {
+		countSQL += " WHERE " + strings.Join(conds, " AND ")
+	}
+	if err := p.pool.QueryRow(ctx, countSQL, args...).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("GetAllGames count: %w", err)
+	}
+
+	op, dir := ">", "ASC"
+	if spec.desc {
+		op, dir = "<", "DESC"
+	}
+	if opts.After != nil {
+		args = append(args, opts.After.Value, opts.After.ID)
+		conds = append(conds, fmt.Sprintf("(%s, game.id) %s ($%d::text::%s, $%d)",
+			spec.key, op, len(args)-1, spec.cast, len(args)))
+	}
+	query := getAllGamesSQL
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	// one extra row tells us whether there is a next page
+	args = append(args, opts.Limit+1)
+	query += fmt.Sprintf(" ORDER BY %s %s, game.id %s LIMIT $%d", spec.key, dir, dir, len(args))
+
+	rows, err := p.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("GetAllGames: %w", err)
 	}
 	defer rows.Close()
 
-	var games []Game
 	for rows.Next() {
 		var game Game
-		var genre genre.Genre
-		if err := rows.Scan(
-			&game.ID,
-			&game.Name,
-			&game.AvgRating,
-			&game.ReviewsCount,
-			&game.Description,
-			&game.ImageURL,
-			&genre.ID,
-			&genre.Name,
-		); err != nil {
+		if err := rows.Scan(gameColumns(&game)...); err != nil {
 			return nil, fmt.Errorf("GetAllGames Scan: %w", err)
 		}
-		game.Genre = genre
-		games = append(games, game)
+		page.Games = append(page.Games, game)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("GetAllGames rows: %w", err)
 	}
-	return games, nil
+
+	if len(page.Games) > opts.Limit {
+		page.Games = page.Games[:opts.Limit]
+		last := &page.Games[len(page.Games)-1]
+		page.NextCursor = Cursor{Sort: opts.Sort, Value: spec.value(last), ID: last.ID}.Encode()
+	}
+	return page, nil
+}
+
+func (p *PostgresRepository) StreamGames(ctx context.Context, fn func(Game) error) error {
+	rows, err := p.pool.Query(ctx, getAllGamesOrderedSQL)
+	if err != nil {
+		return fmt.Errorf("StreamGames: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var game Game
+		if err := rows.Scan(append(gameColumns(&game), &game.ReviewsText)...); err != nil {
+			return fmt.Errorf("StreamGames Scan: %w", err)
+		}
+		if err := fn(game); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("StreamGames rows: %w", err)
+	}
+	return nil
+}
+
+// inTx runs fn in a transaction; game writes use it to record a search outbox event atomically.
+func (p *PostgresRepository) inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
+
 func (p *PostgresRepository) AddGame(ctx context.Context, game *Game) error {
-	_, err := p.pool.Exec(ctx, addGameSQL, game.Name, game.Description, game.ImageURL, game.Genre.ID)
+	err := p.inTx(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, addGameSQL, game.Name, game.Description, game.ImageURL).Scan(&game.ID)
+		if err != nil {
+			return err
+		}
+		if err := addGameLinks(ctx, tx, []*Game{game}); err != nil {
+			return err
+		}
+		return addOutboxEvent(ctx, tx, game.ID, OutboxIndex)
+	})
+	if err != nil {
+		return fmt.Errorf("AddGame: %w", nameTaken(err))
+	}
+	return nil
+}
+
+func (p *PostgresRepository) UpdateGame(ctx context.Context, game *Game) error {
+	err := p.inTx(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, updateGameSQL, game.ID, game.Name, game.Description, game.ImageURL)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		if _, err := tx.Exec(ctx, removeGameGenresSQL, game.ID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, removeGameTagsSQL, game.ID); err != nil {
+			return err
+		}
+		if err := addGameLinks(ctx, tx, []*Game{game}); err != nil {
+			return err
+		}
+		return addOutboxEvent(ctx, tx, game.ID, OutboxIndex)
+	})
 	if err != nil {
-		return fmt.Errorf("AddGame: %w", err)
+		return fmt.Errorf("UpdateGame: %w", nameTaken(err))
+	}
+	return nil
+}
+
+// nameTaken reports the unique violation on games.name as ErrGameExists.
+func nameTaken(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "games_name_key" {
+		return ErrGameExists
+	}
+	return err
+}
+
+// addGameLinks stores the genres and tags of games, which must have their ids.
+func addGameLinks(ctx context.Context, tx pgx.Tx, games []*Game) error {
+	var genreGames, genreIDs, tagGames, tagIDs []int
+	for _, game := range games {
+		for _, g := range game.Genres {
+			genreGames, genreIDs = append(genreGames, game.ID), append(genreIDs, g.ID)
+		}
+		for _, t := range game.Tags {
+			tagGames, tagIDs = append(tagGames, game.ID), append(tagIDs, t.ID)
+		}
+	}
+	if _, err := tx.Exec(ctx, addGameGenresSQL, genreGames, genreIDs); err != nil {
+		return fmt.Errorf("addGameLinks genres: %w", err)
+	}
+	if _, err := tx.Exec(ctx, addGameTagsSQL, tagGames, tagIDs); err != nil {
+		return fmt.Errorf("addGameLinks tags: %w", err)
 	}
 	return nil
 }
 
 func (p *PostgresRepository) RemoveGameByID(ctx context.Context, id int) error {
-	_, err := p.pool.Exec(ctx, removeGameSQL, id)
+	err := p.inTx(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, removeGameSQL, id); err != nil {
+			return err
+		}
+		return addOutboxEvent(ctx, tx, id, OutboxDelete)
+	})
 	if err != nil {
 		return fmt.Errorf("RemoveGameByID: %w", err)
 	}
 	return nil
 }
+
+// importBatchSize bounds the arrays sent in one INSERT.
+const importBatchSize = 1000
+
+func (p *PostgresRepository) ImportGames(ctx context.Context, games []Game) error {
+	err := p.inTx(ctx, func(tx pgx.Tx) error {
+		err := resolveLinks(games,
+			func(names []string) ([]genre.Genre, error) { return genre.UpsertGenresTx(ctx, tx, names) },
+			func(names []string) ([]tag.Tag, error) { return tag.UpsertTagsTx(ctx, tx, names) })
+		if err != nil {
+			return err
+		}
+		index := make(map[string]*Game, len(games))
+		for i := range games {
+			index[games[i].Name] = &games[i]
+		}
+
+		var ids []int
+		for batch := range slices.Chunk(games, importBatchSize) {
+			var names, descriptions, imageURLs []string
+			for _, g := range batch {
+				names = append(names, g.Name)
+				descriptions = append(descriptions, g.Description)
+				imageURLs = append(imageURLs, g.ImageURL)
+			}
+			// taken names, even by a concurrent writer, come back without a row
+			rows, err := tx.Query(ctx, importGamesSQL, names, descriptions, imageURLs)
+			if err != nil {
+				return err
+			}
+			var added []*Game
+			var id int
+			var name string
+			_, err = pgx.ForEachRow(rows, []any{&id, &name}, func() error {
+				index[name].ID = id
+				added = append(added, index[name])
+				ids = append(ids, id)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if err := addGameLinks(ctx, tx, added); err != nil {
+				return err
+			}
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return addOutboxEvents(ctx, tx, ids, OutboxIndex)
+	})
+	if err != nil {
+		for i := range games {
+			games[i].ID = 0
+		}
+		return fmt.Errorf("ImportGames: %w", err)
+	}
+	return nil
+}
+
+// resolveLinks fills in the genre and tag ids of games; the upsert functions create
+// the missing ones, inside the import transaction for Postgres.
+func resolveLinks(
+	games []Game,
+	upsertGenres func(names []string) ([]genre.Genre, error),
+	upsertTags func(names []string) ([]tag.Tag, error),
+) error {
+	genreIDs, tagIDs := map[string]int{}, map[string]int{}
+	var genreNames, tagNames []string
+	for _, g := range games {
+		for _, ge := range g.Genres {
+			if _, ok := genreIDs[ge.Name]; !ok {
+				genreIDs[ge.Name] = 0
+				genreNames = append(genreNames, ge.Name)
+			}
+		}
+		for _, t := range g.Tags {
+			if _, ok := tagIDs[t.Name]; !ok {
+				tagIDs[t.Name] = 0
+				tagNames = append(tagNames, t.Name)
+			}
+		}
+	}
+	genres, err := upsertGenres(genreNames)
+	if err != nil {
+		return err
+	}
+	for _, ge := range genres {
+		genreIDs[ge.Name] = ge.ID
+	}
+	tags, err := upsertTags(tagNames)
+	if err != nil {
+		return err
+	}
+	for _, t := range tags {
+		tagIDs[t.Name] = t.ID
+	}
+	for i := range games {
+		for j := range games[i].Genres {
+			games[i].Genres[j].ID = genreIDs[games[i].Genres[j].Name]
+		}
+		for j := range games[i].Tags {
+			games[i].Tags[j].ID = tagIDs[games[i].Tags[j].Name]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package game
+
+import "encoding/json"
+
+// Query is one clause of the Elasticsearch query DSL. Every clause is built from
+// typed fields and marshalled by encoding/json, so user input is always a JSON value.
+type Query interface {
+	json.Marshaler
+}
+
+type MatchAllQuery struct{}
+
+func (q MatchAllQuery) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{"match_all": struct{}{}})
+}
+
+type MultiMatchQuery struct {
+	Query     string   `json:"query"`
+	Fields    []string `json:"fields,omitempty"`
+	Type      string   `json:"type,omitempty"`
+	Operator  string   `json:"operator,omitempty"`
+	Fuzziness string   `json:"fuzziness,omitempty"`
+}
+
+func (q MultiMatchQuery) MarshalJSON() ([]byte, error) {
+	type body MultiMatchQuery
+	return json.Marshal(map[string]any{"multi_match": body(q)})
+}
+
+type TermQuery struct {
+	Field string
+	Value any
+}
+
+func (q TermQuery) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{"term": map[string]any{q.Field: q.Value}})
+}
+
+type TermsQuery struct {
+	Field  string
+	Values []any
+}
+
+func (q TermsQuery) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{"terms": map[string]any{q.Field: q.Values}})
+}
+
+// RangeQuery bounds are inclusive; nil means unbounded.
+type RangeQuery struct {
+	Field string
+	Gte   any
+	Lte   any
+}
+
+func (q RangeQuery) MarshalJSON() ([]byte, error) {
+	bounds := map[string]any{}
+	if q.Gte != nil {
+		bounds["gte"] = q.Gte
+	}
+	if q.Lte != nil {
+		bounds["lte"] = q.Lte
+	}
+	return json.Marshal(map[string]any{"range": map[string]any{q.Field: bounds}})
+}
+
+// MoreLikeThisQuery finds documents sharing significant terms with the liked ones,
+// which are themselves excluded.
+type MoreLikeThisQuery struct {
+	Fields        []string
+	LikeIDs       []string
+	MinTermFreq   int
+	MinDocFreq    int
+	MaxQueryTerms int
+}
+
+func (q MoreLikeThisQuery) MarshalJSON() ([]byte, error) {
+	like := make([]map[string]string, len(q.LikeIDs))
+	for i, id := range q.LikeIDs {
+		like[i] = map[string]string{"_id": id}
+	}
+	body := map[string]any{"fields": q.Fields, "like": like}
+	if q.MinTermFreq > 0 {
+		body["min_term_freq"] = q.MinTermFreq
+	}
+	if q.MinDocFreq > 0 {
+		body["min_doc_freq"] = q.MinDocFreq
+	}
+	if q.MaxQueryTerms > 0 {
+		body["max_query_terms"] = q.MaxQueryTerms
+	}
+	return json.Marshal(map[string]any{"more_like_this": body})
+}
+
+type IDsQuery struct {
+	Values []string
+}
+
+func (q IDsQuery) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{"ids": map[string]any{"values": q.Values}})
+}
+
+type BoolQuery struct {
+	Must               []Query `json:"must,omitempty"`
+	Filter             []Query `json:"filter,omitempty"`
+	Should             []Query `json:"should,omitempty"`
+	MustNot            []Query `json:"must_not,omitempty"`
+	MinimumShouldMatch int     `json:"minimum_should_match,omitempty"`
+}
+
+func (q BoolQuery) MarshalJSON() ([]byte, error) {
+	type body BoolQuery
+	return json.Marshal(map[string]any{"bool": body(q)})
+}
+
+type SortField struct {
+	Field   string
+	Order   string // "asc" or "desc"
+	Missing string // "_first" or "_last"
+}
+
+func (s SortField) MarshalJSON() ([]byte, error) {
+	opts := map[string]any{"order": s.Order}
+	if s.Missing != "" {
+		opts["missing"] = s.Missing
+	}
+	return json.Marshal(map[string]any{s.Field: opts})
+}
+
+type HighlightField struct {
+	FragmentSize      int `json:"fragment_size,omitempty"`
+	NumberOfFragments int `json:"number_of_fragments,omitempty"`
+}
+
+type Highlight struct {
+	PreTags  []string `json:"pre_tags,omitempty"`
+	PostTags []string `json:"post_tags,omitempty"`
+	// Encoder "html" escapes the text around the tags.
+	Encoder string                    `json:"encoder,omitempty"`
+	Fields  map[string]HighlightField `json:"fields"`
+}
+
+// Aggregation is one named aggregation of a _search call.
+type Aggregation interface {
+	json.Marshaler
+}
+
+type TermsAggregation struct {
+	Field string
+	Size  int
+}
+
+func (a TermsAggregation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{"terms": map[string]any{"field": a.Field, "size": a.Size}})
+}
+
+// AggregationRange is [From, To); nil means unbounded.
+type AggregationRange struct {
+	Key  string   `json:"key"`
+	From *float64 `json:"from,omitempty"`
+	To   *float64 `json:"to,omitempty"`
+}
+
+type RangeAggregation struct {
+	Field  string
+	Ranges []AggregationRange
+}
+
+func (a RangeAggregation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{"range": map[string]any{"field": a.Field, "ranges": a.Ranges}})
+}
+
+// FilterAggregation narrows the documents its sub-aggregations see.
+type FilterAggregation struct {
+	Filter Query
+	Aggs   map[string]Aggregation
+}
+
+func (a FilterAggregation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{"filter": a.Filter, "aggs": a.Aggs})
+}
+
+type Suggester interface {
+	json.Marshaler
+}
+
+// CompletionSuggester looks up a prefix in a completion field.
+type CompletionSuggester struct {
+	Prefix         string
+	Field          string
+	Size           int
+	SkipDuplicates bool
+}
+
+func (s CompletionSuggester) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"prefix": s.Prefix,
+		"completion": map[string]any{
+			"field":           s.Field,
+			"size":            s.Size,
+			"skip_duplicates": s.SkipDuplicates,
+		},
+	})
+}
+
+// SearchBody is the request body of a _search call.
+// PostFilter applies to hits only, so aggregations can ignore it.
+type SearchBody struct {
+	Query      Query                  `json:"query,omitempty"`
+	PostFilter Query                  `json:"post_filter,omitempty"`
+	From       int                    `json:"from,omitempty"`
+	Size       int                    `json:"size,omitempty"`
+	Sort       []SortField            `json:"sort,omitempty"`
+	Highlight  *Highlight             `json:"highlight,omitempty"`
+	Aggs       map[string]Aggregation `json:"aggs,omitempty"`
+	Suggest    map[string]Suggester   `json:"suggest,omitempty"`
+	Source     []string               `json:"_source,omitempty"`
+	// SearchAfter continues from the sort values of the last hit of the previous page.
+	SearchAfter []any `json:"search_after,omitempty"`
+}
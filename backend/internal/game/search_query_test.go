@@ -0,0 +1,72 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// assertGolden compares the indented JSON of body with testdata/name.json.
+func assertGolden(t *testing.T, name string, body any) {
+	t.Helper()
+	got, err := json.MarshalIndent(body, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+	path := filepath.Join("testdata", name+".json")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("%s differs from the golden file, rerun with -update and review the git diff:\n%s", path, got)
+	}
+}
+
+func TestBuildSearchBody(t *testing.T) {
+	minRating, maxRating := 6.5, 9.0
+	tests := []struct {
+		golden string
+		req    SearchRequest
+	}{
+		{"search_query_only", SearchRequest{Query: "witcher", Size: 20}},
+		{"search_genres", SearchRequest{Query: "rpg", Genres: []string{"RPG", "Strategy"}, Size: 20}},
+		{"search_rating", SearchRequest{Query: "rpg", MinRating: &minRating, Size: 20}},
+		{"search_all_filters", SearchRequest{
+			Query:      `"open world" -zombies`,
+			Genres:     []string{"RPG"},
+			Tags:       []string{"co-op", "story rich"},
+			MinRating:  &minRating,
+			MaxRating:  &maxRating,
+			MinReviews: 3,
+			Sort:       SortByRating,
+			From:       40,
+			Size:       20,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			assertGolden(t, tt.golden, buildSearchBody(tt.req))
+		})
+	}
+}
+
+func TestBuildSuggestBody(t *testing.T) {
+	assertGolden(t, "suggest", buildSuggestBody(`ведь"`, 5))
+}
+
+func TestBuildSimilarBody(t *testing.T) {
+	assertGolden(t, "similar", buildSimilarBody(42, 10))
+}
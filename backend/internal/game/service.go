@@ -6,27 +6,50 @@ import (
 	"fmt"
 	"github.com/jackc/pgx/v5"
 	"igropoisk_backend/internal/game/genre"
+	"igropoisk_backend/internal/game/tag"
 	"igropoisk_backend/internal/logger"
 	"igropoisk_backend/internal/middleware"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Service interface {
 	AddGame(ctx context.Context, request AddGameRequest) error
+	UpdateGame(ctx context.Context, id int, request UpdateGameRequest) (*Game, error)
 	DeleteGameByID(ctx context.Context, id int) error
 	GetGameByID(ctx context.Context, id int) (*Game, error)
 	GetGameByName(ctx context.Context, name string) (*Game, error)
-	GetAllGames(ctx context.Context) ([]Game, error)
-	SearchGames(ctx context.Context, query string) ([]Game, error)
+	GetAllGames(ctx context.Context, opts ListOptions) (*Page, error)
+	SearchGames(ctx context.Context, req SearchRequest) (*SearchResult, error)
+	SuggestGames(ctx context.Context, prefix string, size int) ([]Suggestion, error)
+	SimilarGames(ctx context.Context, id int, size int) ([]Game, error)
+	Reindex(ctx context.Context) (*ReindexResult, error)
+	VerifyIndex(ctx context.Context) (*IndexDiff, error)
+	ImportGames(ctx context.Context, records []ImportRecord) (*ImportReport, error)
+	ListGenres(ctx context.Context) ([]genre.Genre, error)
+	ListTags(ctx context.Context) ([]tag.Tag, error)
+	AddGameTags(ctx context.Context, id int, tags []string) (*Game, error)
+	RemoveGameTag(ctx context.Context, id int, name string) (*Game, error)
 }
+
+var (
+	ErrGameNotFound = errors.New("game not found")
+	ErrGameExists   = errors.New("a game with this name already exists")
+)
+
 type service struct {
 	gameRepo   Repository
 	genreRepo  genre.Repository
+	tagRepo    tag.Repository
 	searchRepo SearchRepository
 }
 
-func NewService(gameRepo Repository, genreRepo genre.Repository, searchRepo SearchRepository) Service {
-	return &service{gameRepo: gameRepo, genreRepo: genreRepo, searchRepo: searchRepo}
+func NewService(gameRepo Repository, genreRepo genre.Repository, tagRepo tag.Repository, searchRepo SearchRepository) Service {
+	return &service{gameRepo: gameRepo, genreRepo: genreRepo, tagRepo: tagRepo, searchRepo: searchRepo}
 }
 
 func validateGame(game Game) (bool, error) {
@@ -45,45 +68,130 @@ func validateGame(game Game) (bool, error) {
 	return true, nil
 }
 
-func (s *service) AddGame(ctx context.Context, request AddGameRequest) error {
-	genre, err := s.genreRepo.GetGenreByName(ctx, request.Genre)
-	if err != nil {
-		fmt.Println(err)
-		if errors.Is(err, pgx.ErrNoRows) {
-			genre, err = s.genreRepo.AddGenre(ctx, request.Genre)
-			if err != nil {
-				logger.Logger.Error(
-					"Failed to add genre",
-					"genre_name", request.Genre,
-					"user_id", ctx.Value(middleware.UserIDKey),
-					"error", err,
-				)
-				return errors.New("failed to add a new genre")
-			}
-		} else {
-			logger.Logger.Error(
-				"Failed to find genre",
-				"genre_name", request.Genre,
-				"user_id", ctx.Value(middleware.UserIDKey),
-				"error", err,
-			)
-			return errors.New("failed to find a genre")
+const (
+	MaxGameGenres = 5
+	MaxGameTags   = 20
+	MaxTagLength  = 32
+)
+
+// normalizeGenres trims the names and drops repeats, keeping the order.
+func normalizeGenres(names []string) ([]string, error) {
+	var genres []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, errors.New("genre must not be empty")
+		}
+		if !slices.Contains(genres, name) {
+			genres = append(genres, name)
+		}
+	}
+	if len(genres) == 0 {
+		return nil, errors.New("at least one genre is required")
+	}
+	if len(genres) > MaxGameGenres {
+		return nil, fmt.Errorf("a game has at most %d genres", MaxGameGenres)
+	}
+	return genres, nil
+}
+
+// normalizeTag lower cases the name and collapses its spaces, so "Pixel  Art" is "pixel art".
+func normalizeTag(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// normalizeTags normalizes the names and drops repeats, keeping the order.
+func normalizeTags(names []string) ([]string, error) {
+	tags := []string{}
+	for _, name := range names {
+		name = normalizeTag(name)
+		if name == "" {
+			return nil, errors.New("tag must not be empty")
+		}
+		if len([]rune(name)) > MaxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", name, MaxTagLength)
+		}
+		if !slices.Contains(tags, name) {
+			tags = append(tags, name)
 		}
 	}
+	if len(tags) > MaxGameTags {
+		return nil, fmt.Errorf("a game has at most %d tags", MaxGameTags)
+	}
+	return tags, nil
+}
+
+// resolveGenres finds genres by name, creating the missing ones. They are ordered by
+// name, like the repository returns them.
+func (s *service) resolveGenres(ctx context.Context, names []string) ([]genre.Genre, error) {
+	genres, err := s.genreRepo.UpsertGenres(ctx, names)
+	if err != nil {
+		logger.Logger.Error(
+			"Failed to add genres",
+			"genre_names", names,
+			"user_id", ctx.Value(middleware.UserIDKey),
+			"error", err,
+		)
+		return nil, errors.New("failed to add genres")
+	}
+	slices.SortFunc(genres, func(a, b genre.Genre) int { return strings.Compare(a.Name, b.Name) })
+	return genres, nil
+}
+
+// resolveTags is resolveGenres for tags.
+func (s *service) resolveTags(ctx context.Context, names []string) ([]tag.Tag, error) {
+	if len(names) == 0 {
+		return []tag.Tag{}, nil
+	}
+	tags, err := s.tagRepo.UpsertTags(ctx, names)
+	if err != nil {
+		logger.Logger.Error(
+			"Failed to add tags",
+			"tag_names", names,
+			"user_id", ctx.Value(middleware.UserIDKey),
+			"error", err,
+		)
+		return nil, errors.New("failed to add tags")
+	}
+	slices.SortFunc(tags, func(a, b tag.Tag) int { return strings.Compare(a.Name, b.Name) })
+	return tags, nil
+}
+
+func normalizeName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	first, size := utf8.DecodeRuneInString(name)
+	if size == 0 {
+		return name
+	}
+	return string(unicode.ToUpper(first)) + name[size:]
+}
 
-	var game Game = Game{Name: request.Name, Description: request.Description, ImageURL: request.ImageURL, Genre: *genre}
+func (s *service) AddGame(ctx context.Context, request AddGameRequest) error {
+	var game Game = Game{Name: request.Name, Description: request.Description, ImageURL: request.ImageURL}
 
 	if valid, err := validateGame(game); !valid {
 		return err
 	}
-
-	name := strings.TrimSpace(game.Name)
-	if len(name) > 0 {
-		name = strings.ToLower(name)
-		name = strings.ToUpper(string(name[0])) + name[1:]
+	genreNames, err := normalizeGenres(request.Genres)
+	if err != nil {
+		return err
+	}
+	tagNames, err := normalizeTags(request.Tags)
+	if err != nil {
+		return err
+	}
+	if game.Genres, err = s.resolveGenres(ctx, genreNames); err != nil {
+		return err
+	}
+	if game.Tags, err = s.resolveTags(ctx, tagNames); err != nil {
+		return err
 	}
-	game.Name = name
+
+	game.Name = normalizeName(game.Name)
 	err = s.gameRepo.AddGame(ctx, &game)
+	if errors.Is(err, ErrGameExists) {
+		return ErrGameExists
+	}
 	if err != nil {
 		logger.Logger.Error(
 			"Failed to add a new game",
@@ -94,13 +202,69 @@ func (s *service) AddGame(ctx context.Context, request AddGameRequest) error {
 		)
 		return errors.New("failed to add a new game")
 	}
-	if err := s.searchRepo.IndexGame(ctx, &game); err != nil {
-		logger.Logger.Warn("Failed to add a new game to search repo",
-			"game_id", game.ID,
+	return nil
+}
+
+func (s *service) UpdateGame(ctx context.Context, id int, request UpdateGameRequest) (*Game, error) {
+	game, err := s.gameRepo.GetGameByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrGameNotFound
+		}
+		logger.Logger.Error("Failed to get a game",
+			"game_id", id,
 			"user_id", ctx.Value(middleware.UserIDKey),
 			"error", err)
+		return nil, errors.New("failed to get a game")
 	}
-	return nil
+
+	if request.Name != nil {
+		game.Name = *request.Name
+	}
+	if request.Description != nil {
+		game.Description = *request.Description
+	}
+	if request.ImageURL != nil {
+		game.ImageURL = *request.ImageURL
+	}
+
+	if valid, err := validateGame(*game); !valid {
+		return nil, err
+	}
+	if request.Genres != nil {
+		names, err := normalizeGenres(*request.Genres)
+		if err != nil {
+			return nil, err
+		}
+		if game.Genres, err = s.resolveGenres(ctx, names); err != nil {
+			return nil, err
+		}
+	}
+	if request.Tags != nil {
+		names, err := normalizeTags(*request.Tags)
+		if err != nil {
+			return nil, err
+		}
+		if game.Tags, err = s.resolveTags(ctx, names); err != nil {
+			return nil, err
+		}
+	}
+	game.Name = normalizeName(game.Name)
+
+	if err := s.gameRepo.UpdateGame(ctx, game); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrGameNotFound
+		}
+		if errors.Is(err, ErrGameExists) {
+			return nil, ErrGameExists
+		}
+		logger.Logger.Error("Failed to update a game",
+			"game_id", id,
+			"user_id", ctx.Value(middleware.UserIDKey),
+			"error", err)
+		return nil, errors.New("failed to update a game")
+	}
+	return game, nil
 }
 
 func (s *service) DeleteGameByID(ctx context.Context, id int) error {
@@ -121,13 +285,6 @@ func (s *service) DeleteGameByID(ctx context.Context, id int) error {
 		)
 		return errors.New("failed to remove a game")
 	}
-
-	if err := s.searchRepo.DeleteGame(ctx, id); err != nil {
-		logger.Logger.Error("Failed to remove a game",
-			"game_id", id,
-			"user_id", ctx.Value(middleware.UserIDKey),
-			"error", err)
-	}
 	return nil
 }
 
@@ -141,6 +298,9 @@ func (s *service) GetGameByID(ctx context.Context, id int) (*Game, error) {
 	}
 	game, err := s.gameRepo.GetGameByID(ctx, id)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrGameNotFound
+		}
 		logger.Logger.Error("Failed to get a game",
 			"game_id", id,
 			"user_id", ctx.Value(middleware.UserIDKey),
@@ -156,8 +316,12 @@ func (s *service) GetGameByName(ctx context.Context, name string) (*Game, error)
 			"game_name", name,
 			"user_id", ctx.Value(middleware.UserIDKey))
 	}
-	game, err := s.gameRepo.GetGameByName(ctx, name)
+	// names are stored normalized
+	game, err := s.gameRepo.GetGameByName(ctx, normalizeName(name))
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrGameNotFound
+		}
 		logger.Logger.Error("Failed to get a game",
 			"game_name", name,
 			"error", err)
@@ -166,25 +330,273 @@ func (s *service) GetGameByName(ctx context.Context, name string) (*Game, error)
 	return game, nil
 }
 
-func (s *service) GetAllGames(ctx context.Context) ([]Game, error) {
-	games, err := s.gameRepo.GetAllGames(ctx)
+func (s *service) GetAllGames(ctx context.Context, opts ListOptions) (*Page, error) {
+	if opts.Sort == "" {
+		opts.Sort = SortByName
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultPageSize
+	}
+	if opts.Limit > MaxPageSize {
+		opts.Limit = MaxPageSize
+	}
+	if opts.After != nil && opts.After.Sort != opts.Sort {
+		return nil, errors.New("cursor does not match sort order")
+	}
+	page, err := s.gameRepo.GetAllGames(ctx, opts)
 	if err != nil {
 		logger.Logger.Error("Failed to get all games",
 			"user_id", ctx.Value(middleware.UserIDKey),
 			"error", err)
 		return nil, errors.New("failed to get all games")
 	}
-	return games, nil
+	return page, nil
 }
 
-func (s *service) SearchGames(ctx context.Context, query string) ([]Game, error) {
-	games, err := s.searchRepo.SearchGames(ctx, query)
+func (s *service) SearchGames(ctx context.Context, req SearchRequest) (*SearchResult, error) {
+	if req.Size <= 0 {
+		req.Size = DefaultPageSize
+	}
+	if req.Size > MaxPageSize {
+		req.Size = MaxPageSize
+	}
+	result, err := s.searchRepo.SearchGames(ctx, req)
 	if err != nil {
 		logger.Logger.Error("Failed to search games",
 			"user_id", ctx.Value(middleware.UserIDKey),
-			"query", query,
+			"query", req.Query,
 			"error", err)
 		return nil, errors.New("failed to search games")
 	}
+	return result, nil
+}
+
+const (
+	DefaultSuggestions = 8
+	MaxSuggestions     = 20
+	// suggestions are requested on every keystroke, a late answer is useless
+	suggestTimeout = 300 * time.Millisecond
+)
+
+func (s *service) SuggestGames(ctx context.Context, prefix string, size int) ([]Suggestion, error) {
+	if size <= 0 || size > MaxSuggestions {
+		size = DefaultSuggestions
+	}
+	ctx, cancel := context.WithTimeout(ctx, suggestTimeout)
+	defer cancel()
+	suggestions, err := s.searchRepo.SuggestGames(ctx, prefix, size)
+	if err != nil {
+		logger.Logger.Warn("Failed to suggest games",
+			"user_id", ctx.Value(middleware.UserIDKey),
+			"prefix", prefix,
+			"error", err)
+		return nil, errors.New("failed to suggest games")
+	}
+	return suggestions, nil
+}
+
+const DefaultSimilarGames = 6
+
+// SimilarGames falls back to the best rated games of the first genre when search is unavailable.
+func (s *service) SimilarGames(ctx context.Context, id int, size int) ([]Game, error) {
+	if size <= 0 || size > MaxPageSize {
+		size = DefaultSimilarGames
+	}
+	game, err := s.gameRepo.GetGameByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrGameNotFound
+		}
+		logger.Logger.Error("Failed to get a game",
+			"game_id", id,
+			"user_id", ctx.Value(middleware.UserIDKey),
+			"error", err)
+		return nil, errors.New("failed to get a game")
+	}
+
+	games, err := s.searchRepo.SimilarGames(ctx, id, size)
+	if err == nil {
+		return games, nil
+	}
+	logger.Logger.Warn("Failed to find similar games in search, falling back to genre",
+		"game_id", id,
+		"error", err)
+	if len(game.Genres) == 0 {
+		return []Game{}, nil
+	}
+
+	page, err := s.gameRepo.GetAllGames(ctx, ListOptions{GenreID: game.Genres[0].ID, Sort: SortByRating, Limit: size + 1})
+	if err != nil {
+		logger.Logger.Error("Failed to get games of the same genre",
+			"game_id", id,
+			"genre_id", game.Genres[0].ID,
+			"error", err)
+		return nil, errors.New("failed to find similar games")
+	}
+	games = make([]Game, 0, size)
+	for _, g := range page.Games {
+		if g.ID != id && len(games) < size {
+			games = append(games, g)
+		}
+	}
 	return games, nil
 }
+
+func (s *service) Reindex(ctx context.Context) (*ReindexResult, error) {
+	result, err := s.searchRepo.Reindex(ctx, s.gameRepo)
+	if err != nil {
+		logger.Logger.Error("Failed to rebuild search index",
+			"user_id", ctx.Value(middleware.UserIDKey),
+			"error", err)
+		// the partial result carries per-document errors
+		return result, errors.New("failed to rebuild search index")
+	}
+	return result, nil
+}
+
+func (s *service) VerifyIndex(ctx context.Context) (*IndexDiff, error) {
+	streamer, ok := s.searchRepo.(IndexStreamer)
+	if !ok {
+		return nil, ErrIndexNotVerifiable
+	}
+	diff, err := DiffIndex(ctx, s.gameRepo, streamer)
+	if err != nil {
+		if errors.Is(err, ErrIndexNotVerifiable) {
+			return nil, err
+		}
+		logger.Logger.Error("Failed to verify search index",
+			"error", err)
+		return nil, errors.New("failed to verify search index")
+	}
+	return diff, nil
+}
+
+// ImportGames validates every record like AddGame, then adds the valid ones in a single
+// transaction. Rows repeating a name, from the catalog or an earlier row, are skipped.
+func (s *service) ImportGames(ctx context.Context, records []ImportRecord) (*ImportReport, error) {
+	report := &ImportReport{Rows: make([]ImportRowResult, len(records))}
+	var (
+		games    []Game
+		gameRows []int // index in report.Rows of every entry in games
+	)
+	firstLine := map[string]int{}
+	for i, record := range records {
+		row := &report.Rows[i]
+		row.Line, row.Name = record.Line, record.Request.Name
+		if record.Err != nil {
+			row.Status, row.Reason = ImportFailed, record.Err.Error()
+			continue
+		}
+		req := record.Request
+		if err := validateAddGameRequest(req); err != nil {
+			row.Status, row.Reason = ImportFailed, err.Error()
+			continue
+		}
+		game := Game{Name: req.Name, Description: req.Description, ImageURL: req.ImageURL}
+		if valid, err := validateGame(game); !valid {
+			row.Status, row.Reason = ImportFailed, err.Error()
+			continue
+		}
+		genres, err := normalizeGenres(req.Genres)
+		if err != nil {
+			row.Status, row.Reason = ImportFailed, err.Error()
+			continue
+		}
+		tags, err := normalizeTags(req.Tags)
+		if err != nil {
+			row.Status, row.Reason = ImportFailed, err.Error()
+			continue
+		}
+		game.Name = normalizeName(game.Name)
+		row.Name = game.Name
+		if line, ok := firstLine[game.Name]; ok {
+			row.Status, row.Reason = ImportSkipped, "duplicate of line "+strconv.Itoa(line)
+			continue
+		}
+		firstLine[game.Name] = record.Line
+		// the repository creates missing genres and tags and fills in the ids
+		for _, name := range genres {
+			game.Genres = append(game.Genres, genre.Genre{Name: name})
+		}
+		game.Tags = []tag.Tag{}
+		for _, name := range tags {
+			game.Tags = append(game.Tags, tag.Tag{Name: name})
+		}
+		slices.SortFunc(game.Genres, func(a, b genre.Genre) int { return strings.Compare(a.Name, b.Name) })
+		slices.SortFunc(game.Tags, func(a, b tag.Tag) int { return strings.Compare(a.Name, b.Name) })
+		games = append(games, game)
+		gameRows = append(gameRows, i)
+	}
+
+	if len(games) > 0 {
+		if err := s.gameRepo.ImportGames(ctx, games); err != nil {
+			logger.Logger.Error("Failed to import games",
+				"games", len(games),
+				"user_id", ctx.Value(middleware.UserIDKey),
+				"error", err)
+			return nil, errors.New("failed to import games")
+		}
+		for i, game := range games {
+			row := &report.Rows[gameRows[i]]
+			if game.ID == 0 {
+				row.Status, row.Reason = ImportSkipped, "already exists"
+			} else {
+				row.Status, row.ID = ImportCreated, game.ID
+			}
+		}
+	}
+
+	report.count()
+	logger.Logger.Info("Imported games",
+		"created", report.Created,
+		"skipped", report.Skipped,
+		"failed", report.Failed,
+		"user_id", ctx.Value(middleware.UserIDKey))
+	return report, nil
+}
+
+func (s *service) ListGenres(ctx context.Context) ([]genre.Genre, error) {
+	genres, err := s.genreRepo.ListGenres(ctx)
+	if err != nil {
+		logger.Logger.Error("Failed to list genres",
+			"user_id", ctx.Value(middleware.UserIDKey),
+			"error", err)
+		return nil, errors.New("failed to list genres")
+	}
+	return genres, nil
+}
+
+func (s *service) ListTags(ctx context.Context) ([]tag.Tag, error) {
+	tags, err := s.tagRepo.ListTags(ctx)
+	if err != nil {
+		logger.Logger.Error("Failed to list tags",
+			"user_id", ctx.Value(middleware.UserIDKey),
+			"error", err)
+		return nil, errors.New("failed to list tags")
+	}
+	return tags, nil
+}
+
+// AddGameTags adds tags to the ones the game already has.
+func (s *service) AddGameTags(ctx context.Context, id int, tags []string) (*Game, error) {
+	game, err := s.GetGameByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	names := append(game.TagNames(), tags...)
+	return s.UpdateGame(ctx, id, UpdateGameRequest{Tags: &names})
+}
+
+// RemoveGameTag is a no-op when the game does not have the tag.
+func (s *service) RemoveGameTag(ctx context.Context, id int, name string) (*Game, error) {
+	game, err := s.GetGameByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	name = normalizeTag(name)
+	names := slices.DeleteFunc(game.TagNames(), func(t string) bool { return t == name })
+	if len(names) == len(game.Tags) {
+		return game, nil
+	}
+	return s.UpdateGame(ctx, id, UpdateGameRequest{Tags: &names})
+}
@@ -0,0 +1,387 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"igropoisk_backend/internal/game/genre"
+	"igropoisk_backend/internal/game/tag"
+	"slices"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func newTestService(t *testing.T) (Service, *MemoryRepository) {
+	t.Helper()
+	genres, tags := genre.NewMemoryRepository(), tag.NewMemoryRepository()
+	games := NewMemoryRepository(NewMemoryOutboxRepository(), genres, tags)
+	s := NewService(games, genres, tags, NewMemorySearchRepository(games))
+	for _, req := range []AddGameRequest{
+		{Name: "The Witcher 3", Description: "Open world RPG about a monster hunter", ImageURL: "img.png", Genres: []string{"RPG", "Open World"}, Tags: []string{"Story Rich"}},
+		{Name: "Doom", Description: "Fast shooter against demons", ImageURL: "img.png", Genres: []string{"Shooter"}},
+		{Name: "Baldur's Gate 3", Description: "Party based RPG", ImageURL: "img.png", Genres: []string{"RPG"}, Tags: []string{"co-op", "story  rich"}},
+	} {
+		if err := s.AddGame(context.Background(), req); err != nil {
+			t.Fatalf("AddGame(%q): %v", req.Name, err)
+		}
+	}
+	return s, games
+}
+
+func TestGetAllGamesPaginates(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+
+	var names []string
+	opts := ListOptions{Sort: SortByName, Limit: 2}
+	for {
+		page, err := s.GetAllGames(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != 3 {
+			t.Fatalf("Total = %d, want 3", page.Total)
+		}
+		for _, g := range page.Games {
+			names = append(names, g.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		if opts.After, err = DecodeCursor(page.NextCursor); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"Baldur's gate 3", "Doom", "The witcher 3"}
+	if len(names) != len(want) {
+		t.Fatalf("names = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("names = %v, want %v", names, want)
+		}
+	}
+}
+
+func TestGetAllGamesSortsUnratedLast(t *testing.T) {
+	s, games := newTestService(t)
+	ctx := context.Background()
+	rating := 8.5
+	if err := games.UpdateRating(ctx, 2, 3, &rating); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := s.GetAllGames(ctx, ListOptions{Sort: SortByRating, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Games[0].Name != "Doom" {
+		t.Fatalf("first game = %q, want Doom", page.Games[0].Name)
+	}
+	page, err = s.GetAllGames(ctx, ListOptions{Sort: SortByRating, Limit: 10, MinRating: &rating})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 {
+		t.Fatalf("Total with min rating = %d, want 1", page.Total)
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	for in, want := range map[string]string{
+		"  the WITCHER 3 ": "The witcher 3",
+		"ВЕДЬМАК":          "Ведьмак",
+		"ёлки":             "Ёлки",
+		"":                 "",
+	} {
+		if got := normalizeName(in); got != want || !utf8.ValidString(got) {
+			t.Errorf("normalizeName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGameNamesAreUnique(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	err := s.AddGame(ctx, AddGameRequest{Name: "DOOM", Description: "Again", ImageURL: "img.png", Genres: []string{"Shooter"}})
+	if !errors.Is(err, ErrGameExists) {
+		t.Fatalf("AddGame with a taken name: %v, want ErrGameExists", err)
+	}
+	name := "doom"
+	if _, err := s.UpdateGame(ctx, 1, UpdateGameRequest{Name: &name}); !errors.Is(err, ErrGameExists) {
+		t.Fatalf("renaming onto a taken name: %v, want ErrGameExists", err)
+	}
+}
+
+func TestUpdateGameKeepsUnsetFields(t *testing.T) {
+	s, _ := newTestService(t)
+	name := "Doom eternal"
+	g, err := s.UpdateGame(context.Background(), 2, UpdateGameRequest{Name: &name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Name != name || !slices.Equal(g.GenreNames(), []string{"Shooter"}) || g.Description == "" {
+		t.Fatalf("UpdateGame = %+v", g)
+	}
+}
+
+func TestGameNotFound(t *testing.T) {
+	s, _ := newTestService(t)
+	if _, err := s.GetGameByID(context.Background(), 42); !errors.Is(err, ErrGameNotFound) {
+		t.Fatalf("GetGameByID error = %v, want ErrGameNotFound", err)
+	}
+	if _, err := s.SimilarGames(context.Background(), 42, 5); !errors.Is(err, ErrGameNotFound) {
+		t.Fatalf("SimilarGames error = %v, want ErrGameNotFound", err)
+	}
+}
+
+func TestSearchGames(t *testing.T) {
+	s, _ := newTestService(t)
+	result, err := s.SearchGames(context.Background(), SearchRequest{Query: "rpg", Genres: []string{"RPG"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 2 {
+		t.Fatalf("Total = %d, want 2", result.Total)
+	}
+	if len(result.Hits[0].Highlights["description"]) == 0 {
+		t.Fatalf("expected a description highlight, got %+v", result.Hits[0])
+	}
+	if len(result.Facets.Genres) != 2 || result.Facets.Genres[0] != (FacetBucket{Key: "RPG", Count: 2}) {
+		t.Fatalf("genre facet = %+v", result.Facets.Genres)
+	}
+
+	// a page past the last match still reports the total
+	result, err = s.SearchGames(context.Background(), SearchRequest{Query: "rpg", Genres: []string{"RPG"}, From: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Hits) != 0 || result.Total != 2 {
+		t.Fatalf("page past the end = %d hits of %d, want 0 of 2", len(result.Hits), result.Total)
+	}
+}
+
+func TestHighlightEscapesHTML(t *testing.T) {
+	got, ok := highlight(`Doom <script>alert("x")</script> & doom`, []string{"doom"})
+	want := `<em>Doom</em> &lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; &amp; <em>doom</em>`
+	if !ok || got != want {
+		t.Fatalf("highlight = %q, want %q", got, want)
+	}
+	if _, ok := highlight("<em>Quake</em>", []string{"doom"}); ok {
+		t.Fatal("highlighted a text without a match")
+	}
+}
+
+func TestSearchFacetsIgnoreTheirOwnFilter(t *testing.T) {
+	s, games := newTestService(t)
+	ctx := context.Background()
+	high, low := 9.0, 5.0
+	if err := games.UpdateRating(ctx, 1, 1, &high); err != nil {
+		t.Fatal(err)
+	}
+	if err := games.UpdateRating(ctx, 3, 1, &low); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.SearchGames(ctx, SearchRequest{Query: "rpg", MinRating: &high})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 1 {
+		t.Fatalf("Total = %d, want 1", result.Total)
+	}
+	// both ratings stay selectable, the genres follow the rating filter
+	if result.Facets.Ratings[2].Count != 1 || result.Facets.Ratings[4].Count != 1 {
+		t.Fatalf("rating facet = %+v, want one game in 4-6 and one in 8-10", result.Facets.Ratings)
+	}
+	if !slices.Equal(result.Facets.Genres, []FacetBucket{{Key: "Open World", Count: 1}, {Key: "RPG", Count: 1}}) {
+		t.Fatalf("genre facet = %+v", result.Facets.Genres)
+	}
+}
+
+func TestSimilarGamesPrefersSameGenre(t *testing.T) {
+	s, _ := newTestService(t)
+	games, err := s.SimilarGames(context.Background(), 1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 2 || games[0].Name != "Baldur's gate 3" {
+		t.Fatalf("SimilarGames = %+v", games)
+	}
+}
+
+func TestGameTags(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	g, err := s.AddGameTags(ctx, 2, []string{" Pixel   Art", "co-op", "CO-OP"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(g.TagNames(), []string{"co-op", "pixel art"}) {
+		t.Fatalf("tags = %v", g.TagNames())
+	}
+
+	page, err := s.GetAllGames(ctx, ListOptions{Tags: []string{"co-op", "story rich"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || page.Games[0].Name != "Baldur's gate 3" {
+		t.Fatalf("games with both tags = %+v", page.Games)
+	}
+	result, err := s.SearchGames(ctx, SearchRequest{Query: "rpg", Tags: []string{"story rich"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 2 || len(result.Facets.Tags) != 2 || result.Facets.Tags[0] != (FacetBucket{Key: "story rich", Count: 2}) {
+		t.Fatalf("search by tag = %+v", result)
+	}
+
+	if g, err = s.RemoveGameTag(ctx, 2, "Pixel Art"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(g.TagNames(), []string{"co-op"}) {
+		t.Fatalf("tags after remove = %v", g.TagNames())
+	}
+	tags, err := s.ListTags(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 3 {
+		t.Fatalf("ListTags = %+v", tags)
+	}
+}
+
+func TestAddGameGenres(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	err := s.AddGame(ctx, AddGameRequest{Name: "Portal", ImageURL: "img.png", Genres: make([]string, MaxGameGenres+1)})
+	if err == nil {
+		t.Fatal("added a game with empty genres")
+	}
+	err = s.AddGame(ctx, AddGameRequest{Name: "Portal", ImageURL: "img.png", Genres: []string{"Puzzle", "Action", "Puzzle"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := s.GetGameByName(ctx, "portal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(g.GenreNames(), []string{"Action", "Puzzle"}) {
+		t.Fatalf("genres = %v", g.GenreNames())
+	}
+	genres, err := s.ListGenres(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(genres) != 5 {
+		t.Fatalf("ListGenres = %+v", genres)
+	}
+}
+
+type fakeIndex []Game
+
+func (f fakeIndex) StreamIndexedGames(_ context.Context, fn func(Game) error) error {
+	for _, g := range f {
+		if err := fn(g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestDiffIndex(t *testing.T) {
+	_, games := newTestService(t)
+	ctx := context.Background()
+	var indexed fakeIndex
+	err := games.StreamGames(ctx, func(g Game) error {
+		indexed = append(indexed, g)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// drop the first, change the second, add an unknown game
+	missing, stale := indexed[0].ID, indexed[1].ID
+	indexed[1].Description = "outdated"
+	indexed = append(indexed[1:], Game{ID: 100, Name: "Removed"})
+
+	diff, err := DiffIndex(ctx, games, indexed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.InSync() || diff.Checked != 3 {
+		t.Fatalf("diff = %+v, want 3 checked and out of sync", diff)
+	}
+	if len(diff.Missing) != 1 || diff.Missing[0] != missing ||
+		len(diff.Stale) != 1 || diff.Stale[0] != stale ||
+		len(diff.Orphaned) != 1 || diff.Orphaned[0] != 100 {
+		t.Fatalf("diff = %+v, want missing [%d], stale [%d], orphaned [100]", diff, missing, stale)
+	}
+}
+
+func TestGamesChangedSince(t *testing.T) {
+	ctx := context.Background()
+	outbox := NewMemoryOutboxRepository()
+	games := NewMemoryRepository(outbox, genre.NewMemoryRepository(), tag.NewMemoryRepository())
+	for _, name := range []string{"Doom", "Quake", "Heretic"} {
+		if err := games.AddGame(ctx, &Game{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the first two are indexed before the rebuild starts, the third is still pending
+	processed := 0
+	_, err := outbox.ProcessPending(ctx, 2, func(OutboxEvent) (time.Time, error) {
+		processed++
+		return time.Time{}, nil
+	})
+	if err != nil || processed != 2 {
+		t.Fatalf("processed %d events: %v", processed, err)
+	}
+	since := time.Now()
+	if err := games.RemoveGameByID(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := outbox.GamesChangedSince(ctx, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []int{1, 3}) {
+		t.Fatalf("GamesChangedSince = %v, want [1 3]", ids)
+	}
+}
+
+func TestOutboxClaimsEvents(t *testing.T) {
+	ctx := context.Background()
+	outbox := NewMemoryOutboxRepository()
+	games := NewMemoryRepository(outbox, genre.NewMemoryRepository(), tag.NewMemoryRepository())
+	if err := games.AddGame(ctx, &Game{Name: "Doom"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a second dispatcher running while the first one calls the index finds nothing to do
+	n, err := outbox.ProcessPending(ctx, 10, func(OutboxEvent) (time.Time, error) {
+		concurrent, err := outbox.ProcessPending(ctx, 10, func(e OutboxEvent) (time.Time, error) {
+			t.Fatalf("event %d handed out twice", e.ID)
+			return time.Time{}, nil
+		})
+		if err != nil || concurrent != 0 {
+			t.Fatalf("concurrent ProcessPending = %d, %v", concurrent, err)
+		}
+		return time.Now(), errors.New("index unavailable")
+	})
+	if err != nil || n != 1 {
+		t.Fatalf("ProcessPending = %d, %v", n, err)
+	}
+
+	// the failed event is due again at its retry time, not when the claim runs out
+	var attempts []int
+	n, err = outbox.ProcessPending(ctx, 10, func(e OutboxEvent) (time.Time, error) {
+		attempts = append(attempts, e.Attempts)
+		return time.Time{}, nil
+	})
+	if err != nil || n != 1 || !slices.Equal(attempts, []int{1}) {
+		t.Fatalf("retry = %d, %v, attempts %v", n, err, attempts)
+	}
+}
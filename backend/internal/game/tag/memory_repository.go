@@ -0,0 +1,41 @@
+package tag
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+)
+
+// MemoryRepository keeps tags in memory for tests and local development.
+type MemoryRepository struct {
+	mu   sync.RWMutex
+	tags []Tag
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{}
+}
+
+func (m *MemoryRepository) ListTags(ctx context.Context) ([]Tag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tags := slices.Clone(m.tags)
+	slices.SortFunc(tags, func(a, b Tag) int { return cmp.Compare(a.Name, b.Name) })
+	return tags, nil
+}
+
+func (m *MemoryRepository) UpsertTags(ctx context.Context, names []string) ([]Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tags := make([]Tag, 0, len(names))
+	for _, name := range names {
+		i := slices.IndexFunc(m.tags, func(t Tag) bool { return t.Name == name })
+		if i < 0 {
+			m.tags = append(m.tags, Tag{ID: len(m.tags) + 1, Name: name})
+			i = len(m.tags) - 1
+		}
+		tags = append(tags, m.tags[i])
+	}
+	return tags, nil
+}
@@ -0,0 +1 @@
+SELECT id, name FROM tags ORDER BY name
\ No newline at end of file
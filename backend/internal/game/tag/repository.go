@@ -0,0 +1,67 @@
+package tag
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed queries/list_tags.sql
+var listTagsSQL string
+
+//go:embed queries/upsert_tags.sql
+var upsertTagsSQL string
+
+type Repository interface {
+	ListTags(ctx context.Context) ([]Tag, error)
+	// UpsertTags returns the tags named names, creating the missing ones. Names must be distinct.
+	UpsertTags(ctx context.Context, names []string) ([]Tag, error)
+}
+
+type PostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresRepository(pool *pgxpool.Pool) Repository {
+	return &PostgresRepository{pool: pool}
+}
+
+func (p *PostgresRepository) ListTags(ctx context.Context) ([]Tag, error) {
+	rows, err := p.pool.Query(ctx, listTagsSQL)
+	if err != nil {
+		return nil, fmt.Errorf("ListTags: %w", err)
+	}
+	tags, err := pgx.CollectRows(rows, pgx.RowToStructByPos[Tag])
+	if err != nil {
+		return nil, fmt.Errorf("ListTags: %w", err)
+	}
+	return tags, nil
+}
+
+func (p *PostgresRepository) UpsertTags(ctx context.Context, names []string) ([]Tag, error) {
+	return upsertTags(ctx, p.pool, names)
+}
+
+// UpsertTagsTx is UpsertTags inside tx, so new tags commit or roll back with it.
+func UpsertTagsTx(ctx context.Context, tx pgx.Tx, names []string) ([]Tag, error) {
+	return upsertTags(ctx, tx, names)
+}
+
+// querier is a pool or a transaction.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func upsertTags(ctx context.Context, q querier, names []string) ([]Tag, error) {
+	rows, err := q.Query(ctx, upsertTagsSQL, names)
+	if err != nil {
+		return nil, fmt.Errorf("UpsertTags: %w", err)
+	}
+	tags, err := pgx.CollectRows(rows, pgx.RowToStructByPos[Tag])
+	if err != nil {
+		return nil, fmt.Errorf("UpsertTags: %w", err)
+	}
+	return tags, nil
+}
@@ -0,0 +1,7 @@
+package tag
+
+// Tag is a free-form label such as "co-op" or "pixel art"; names are stored lower case.
+type Tag struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
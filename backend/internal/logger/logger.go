@@ -10,23 +10,39 @@ import (
 )
 
 var (
-	Logger  *slog.Logger
+	// Logger writes to stderr until InitLogger, so packages can log in tests.
+	Logger  = slog.Default()
 	logFile *os.File
 )
 
-func InitLogger() error {
-	f, err := os.OpenFile("log.txt", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
+type Options struct {
+	// File is appended to in addition to stdout; empty logs to stdout only.
+	File   string
+	Level  string // debug, info, warn or error
+	Format string // text or json
+}
+
+func InitLogger(opts Options) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
 		return err
 	}
-	logFile = f
-	mw := io.MultiWriter(os.Stdout, f)
-
-	handler := slog.NewTextHandler(mw, &slog.HandlerOptions{
-		Level: slog.LevelDebug,
-	})
+	var w io.Writer = os.Stdout
+	if opts.File != "" {
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		logFile = f
+		w = io.MultiWriter(os.Stdout, f)
+	}
 
-	Logger = slog.New(handler)
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	if opts.Format == "json" {
+		Logger = slog.New(slog.NewJSONHandler(w, handlerOpts))
+	} else {
+		Logger = slog.New(slog.NewTextHandler(w, handlerOpts))
+	}
 	return nil
 }
 
@@ -10,8 +10,14 @@ import (
 
 const UserIDKey = "userID"
 const UserNameKey = "username"
+const UserRoleKey = "role"
+const ClaimsKey = "claims"
 
-func AuthMiddleware() gin.HandlerFunc {
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+func AuthMiddleware(revocations RevocationChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		h := c.GetHeader("Authorization")
 		if h == "" {
@@ -33,8 +39,21 @@ func AuthMiddleware() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		revoked, err := revocations.IsRevoked(c.Request.Context(), claims.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify token"})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "token has been revoked"})
+			c.Abort()
+			return
+		}
 		ctx := context.WithValue(c.Request.Context(), UserIDKey, claims.UserID)
 		ctx = context.WithValue(ctx, UserNameKey, claims.Username)
+		ctx = context.WithValue(ctx, UserRoleKey, claims.Role)
+		ctx = context.WithValue(ctx, ClaimsKey, claims)
 		c.Request = c.Request.WithContext(ctx)
 
 		c.Next()
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"igropoisk_backend/internal/auth"
+	"net/http"
+)
+
+// RequireRole must be attached after AuthMiddleware.
+func RequireRole(min auth.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Request.Context().Value(UserRoleKey).(auth.Role)
+		if !role.AtLeast(min) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
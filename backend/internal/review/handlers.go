@@ -3,6 +3,7 @@ package review
 import (
 	"errors"
 	"github.com/gin-gonic/gin"
+	"igropoisk_backend/internal/auth"
 	"igropoisk_backend/internal/middleware"
 	"igropoisk_backend/internal/user"
 	"net/http"
@@ -16,6 +17,11 @@ type AddReviewRequest struct {
 	User    user.User `json:"-"`
 }
 
+type UpdateReviewRequest struct {
+	Content string `json:"content"`
+	Rating  int    `json:"rating"`
+}
+
 type Handler struct {
 	reviewService Service
 }
@@ -74,3 +80,69 @@ func (h *Handler) GetReviewsByGameID(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"reviews": reviews})
 }
+
+func callerFromContext(c *gin.Context) (user.User, bool) {
+	ctx := c.Request.Context()
+	userID, ok := ctx.Value(middleware.UserIDKey).(int)
+	if !ok {
+		return user.User{}, false
+	}
+	username, _ := ctx.Value(middleware.UserNameKey).(string)
+	role, _ := ctx.Value(middleware.UserRoleKey).(auth.Role)
+	return user.User{ID: userID, Name: username, Role: role}, true
+}
+
+func reviewErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrReviewNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrNotReviewOwner):
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+func (h *Handler) UpdateReview(c *gin.Context) {
+	reviewID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || reviewID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid review ID"})
+		return
+	}
+	var req UpdateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if err := validateRequest(AddReviewRequest{Rating: req.Rating}); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	caller, ok := callerFromContext(c)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "user id not found"})
+		return
+	}
+	if err := h.reviewService.UpdateReview(c.Request.Context(), caller, reviewID, req); err != nil {
+		c.JSON(reviewErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) DeleteReview(c *gin.Context) {
+	reviewID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || reviewID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid review ID"})
+		return
+	}
+	caller, ok := callerFromContext(c)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "user id not found"})
+		return
+	}
+	if err := h.reviewService.RemoveReview(c.Request.Context(), caller, reviewID); err != nil {
+		c.JSON(reviewErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
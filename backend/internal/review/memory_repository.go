@@ -0,0 +1,134 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"igropoisk_backend/internal/game"
+	"sync"
+)
+
+// RatingUpdater stores the aggregated rating of a game.
+type RatingUpdater interface {
+	UpdateRating(ctx context.Context, gameID, reviewsCount int, avgRating *float64) error
+}
+
+// MemoryRepository keeps reviews in memory for tests and local development. Every write
+// recomputes the rating of the affected games, as the update_game_rating trigger does.
+type MemoryRepository struct {
+	mu      sync.RWMutex
+	reviews map[int]Review
+	nextID  int
+	ratings RatingUpdater
+}
+
+func NewMemoryRepository(ratings RatingUpdater) *MemoryRepository {
+	return &MemoryRepository{reviews: make(map[int]Review), nextID: 1, ratings: ratings}
+}
+
+// recomputeRating must be called with mu held.
+func (m *MemoryRepository) recomputeRating(ctx context.Context, gameID int) error {
+	count, sum := 0, 0
+	for _, r := range m.reviews {
+		if r.GameID == gameID {
+			count++
+			sum += r.Rating
+		}
+	}
+	var avg *float64
+	if count >= game.MinReviews {
+		a := float64(sum) / float64(count)
+		avg = &a
+	}
+	return m.ratings.UpdateRating(ctx, gameID, count, avg)
+}
+
+func (m *MemoryRepository) AddReview(ctx context.Context, review Review) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	review.ID = m.nextID
+	m.nextID++
+	m.reviews[review.ID] = review
+	if err := m.recomputeRating(ctx, review.GameID); err != nil {
+		return fmt.Errorf("AddReview: %w", err)
+	}
+	return nil
+}
+
+func (m *MemoryRepository) UpdateReview(ctx context.Context, review Review) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.reviews[review.ID]
+	if !ok {
+		return nil
+	}
+	stored.Rating = review.Rating
+	stored.Description = review.Description
+	m.reviews[review.ID] = stored
+	if err := m.recomputeRating(ctx, stored.GameID); err != nil {
+		return fmt.Errorf("UpdateReview: %w", err)
+	}
+	return nil
+}
+
+func (m *MemoryRepository) RemoveReviewByID(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.reviews[id]
+	if !ok {
+		return nil
+	}
+	delete(m.reviews, id)
+	if err := m.recomputeRating(ctx, stored.GameID); err != nil {
+		return fmt.Errorf("RemoveReviewByID: %w", err)
+	}
+	return nil
+}
+
+func (m *MemoryRepository) GetReviewByID(ctx context.Context, id int) (*Review, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	review, ok := m.reviews[id]
+	if !ok {
+		return nil, nil
+	}
+	return &review, nil
+}
+
+func (m *MemoryRepository) GetReviewsByGameID(ctx context.Context, id int) ([]Review, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var reviews []Review
+	for i := 1; i < m.nextID; i++ {
+		if review, ok := m.reviews[i]; ok && review.GameID == id {
+			reviews = append(reviews, review)
+		}
+	}
+	return reviews, nil
+}
+
+func (m *MemoryRepository) IsGameReviewedByUserID(ctx context.Context, userId, gameId int) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, review := range m.reviews {
+		if review.UserID == userId && review.GameID == gameId {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RecomputeRatings visits the reviewed games only; the others never had a rating.
+func (m *MemoryRepository) RecomputeRatings(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	games := map[int]bool{}
+	for _, review := range m.reviews {
+		games[review.GameID] = true
+	}
+	for gameID := range games {
+		if err := m.recomputeRating(ctx, gameID); err != nil {
+			return 0, fmt.Errorf("RecomputeRatings: %w", err)
+		}
+	}
+	return len(games), nil
+}
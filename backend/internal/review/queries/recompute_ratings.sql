@@ -0,0 +1,5 @@
+WITH recomputed AS (
+    SELECT id, recompute_game_rating(id) FROM games
+)
+INSERT INTO search_outbox (game_id, action)
+SELECT id, 'index' FROM recomputed
\ No newline at end of file
@@ -0,0 +1 @@
+UPDATE reviews SET rating = $2, description = $3 WHERE id = $1
\ No newline at end of file
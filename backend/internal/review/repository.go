@@ -12,6 +12,9 @@ import (
 //go:embed queries/add_review.sql
 var addReviewSQL string
 
+//go:embed queries/update_review.sql
+var updateReviewSQL string
+
 //go:embed queries/remove_review_by_id.sql
 var removeReviewByIDSQL string
 
@@ -24,12 +27,18 @@ var getReviewByGameIDSQL string
 //go:embed queries/is_game_reviewed_by_user_id.sql
 var isGameReviwedByUserIDSQL string
 
+//go:embed queries/recompute_ratings.sql
+var recomputeRatingsSQL string
+
 type Repository interface {
 	AddReview(ctx context.Context, review Review) error
+	UpdateReview(ctx context.Context, review Review) error
 	RemoveReviewByID(ctx context.Context, id int) error
 	GetReviewByID(ctx context.Context, id int) (*Review, error)
 	GetReviewsByGameID(ctx context.Context, id int) ([]Review, error)
 	IsGameReviewedByUserID(ctx context.Context, userId, gameId int) (bool, error)
+	// RecomputeRatings rebuilds every game's rating from its reviews and reports how many games it visited.
+	RecomputeRatings(ctx context.Context) (int, error)
 }
 
 type PostgresRepository struct {
@@ -48,6 +57,14 @@ func (p *PostgresRepository) AddReview(ctx context.Context, review Review) error
 	return nil
 }
 
+func (p *PostgresRepository) UpdateReview(ctx context.Context, review Review) error {
+	_, err := p.pool.Exec(ctx, updateReviewSQL, review.ID, review.Rating, review.Description)
+	if err != nil {
+		return fmt.Errorf("UpdateReview: %w", err)
+	}
+	return nil
+}
+
 func (p *PostgresRepository) RemoveReviewByID(ctx context.Context, id int) error {
 	_, err := p.pool.Exec(ctx, removeReviewByIDSQL, id)
 	if err != nil {
@@ -101,3 +118,11 @@ func (p *PostgresRepository) IsGameReviewedByUserID(ctx context.Context, userId,
 	}
 	return exists, nil
 }
+
+func (p *PostgresRepository) RecomputeRatings(ctx context.Context) (int, error) {
+	tag, err := p.pool.Exec(ctx, recomputeRatingsSQL)
+	if err != nil {
+		return 0, fmt.Errorf("RecomputeRatings: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -4,18 +4,27 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"igropoisk_backend/internal/auth"
 	"igropoisk_backend/internal/game"
 	"igropoisk_backend/internal/logger"
 	"igropoisk_backend/internal/middleware"
+	"igropoisk_backend/internal/user"
 )
 
 type Service interface {
 	AddReview(ctx context.Context, request AddReviewRequest) error
 	GetReviewsByGameID(ctx context.Context, id int) ([]Review, error)
 	GetReviewByID(ctx context.Context, id int) (*Review, error)
-	RemoveReview(ctx context.Context, id int) error
+	UpdateReview(ctx context.Context, caller user.User, id int, request UpdateReviewRequest) error
+	RemoveReview(ctx context.Context, caller user.User, id int) error
+	RecomputeRatings(ctx context.Context) (int, error)
 }
 
+var (
+	ErrReviewNotFound = errors.New("review not found")
+	ErrNotReviewOwner = errors.New("only the author or a moderator can change this review")
+)
+
 type service struct {
 	repo        Repository
 	GameService game.Service
@@ -80,14 +89,63 @@ func (s *service) GetReviewByID(ctx context.Context, id int) (*Review, error) {
 	return review, nil
 }
 
-func (s *service) RemoveReview(ctx context.Context, id int) error {
+// getOwnedReview loads a review the caller is allowed to change.
+func (s *service) getOwnedReview(ctx context.Context, caller user.User, id int) (*Review, error) {
+	review, err := s.GetReviewByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if review == nil {
+		return nil, ErrReviewNotFound
+	}
+	if review.UserID != caller.ID && !caller.Role.AtLeast(auth.RoleModerator) {
+		return nil, ErrNotReviewOwner
+	}
+	return review, nil
+}
+
+func (s *service) UpdateReview(ctx context.Context, caller user.User, id int, request UpdateReviewRequest) error {
+	review, err := s.getOwnedReview(ctx, caller, id)
+	if err != nil {
+		return err
+	}
+	updated, err := NewReview(review.GameID, review.UserID, request.Rating, request.Content)
+	if err != nil {
+		return err
+	}
+	updated.ID = review.ID
+	err = s.repo.UpdateReview(ctx, *updated)
+	if err != nil {
+		logger.Logger.Error("Failed to update review",
+			"review_id", id,
+			"user_id", caller.ID,
+			"error", err)
+		return errors.New("failed to update review")
+	}
+	return nil
+}
+
+func (s *service) RemoveReview(ctx context.Context, caller user.User, id int) error {
+	if _, err := s.getOwnedReview(ctx, caller, id); err != nil {
+		return err
+	}
 	err := s.repo.RemoveReviewByID(ctx, id)
 	if err != nil {
 		logger.Logger.Error("Failed to remove review",
-			"game_id", id,
-			"user_id", ctx.Value(middleware.UserIDKey),
+			"review_id", id,
+			"user_id", caller.ID,
 			"error", err)
 		return errors.New("failed to remove review")
 	}
 	return nil
 }
+
+func (s *service) RecomputeRatings(ctx context.Context) (int, error) {
+	n, err := s.repo.RecomputeRatings(ctx)
+	if err != nil {
+		logger.Logger.Error("Failed to recompute ratings",
+			"error", err)
+		return 0, errors.New("failed to recompute ratings")
+	}
+	return n, nil
+}
@@ -0,0 +1,129 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"igropoisk_backend/internal/auth"
+	"igropoisk_backend/internal/game"
+	"igropoisk_backend/internal/game/genre"
+	"igropoisk_backend/internal/game/tag"
+	"igropoisk_backend/internal/user"
+	"testing"
+)
+
+func newTestService(t *testing.T) (Service, game.Service) {
+	t.Helper()
+	genres, tags := genre.NewMemoryRepository(), tag.NewMemoryRepository()
+	games := game.NewMemoryRepository(nil, genres, tags)
+	gameService := game.NewService(games, genres, tags, game.NewMemorySearchRepository(games))
+	if err := gameService.AddGame(context.Background(), game.AddGameRequest{Name: "Doom", ImageURL: "img.png", Genres: []string{"Shooter"}}); err != nil {
+		t.Fatal(err)
+	}
+	return NewService(NewMemoryRepository(games), gameService), gameService
+}
+
+func addReviews(t *testing.T, s Service, ratings ...int) {
+	t.Helper()
+	existing, err := s.GetReviewsByGameID(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, rating := range ratings {
+		u := user.User{ID: len(existing) + i + 1, Name: "user", Role: auth.RoleUser}
+		if err := s.AddReview(context.Background(), AddReviewRequest{GameID: 1, Rating: rating, User: u}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRatingNeedsMinReviews(t *testing.T) {
+	s, games := newTestService(t)
+	ctx := context.Background()
+
+	addReviews(t, s, 6, 8)
+	g, err := games.GetGameByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.ReviewsCount != 2 || g.AvgRating != nil {
+		t.Fatalf("after 2 reviews: count %d, rating %v", g.ReviewsCount, g.AvgRating)
+	}
+
+	addReviews(t, s, 10)
+	g, _ = games.GetGameByID(ctx, 1)
+	if g.AvgRating == nil || *g.AvgRating != 8 {
+		t.Fatalf("after 3 reviews: rating %v, want 8", g.AvgRating)
+	}
+}
+
+func TestRatingFollowsUpdatesAndRemovals(t *testing.T) {
+	s, games := newTestService(t)
+	ctx := context.Background()
+	addReviews(t, s, 2, 4, 6)
+	author := user.User{ID: 1, Role: auth.RoleUser}
+
+	if err := s.UpdateReview(ctx, author, 1, UpdateReviewRequest{Rating: 8}); err != nil {
+		t.Fatal(err)
+	}
+	g, _ := games.GetGameByID(ctx, 1)
+	if g.AvgRating == nil || *g.AvgRating != 6 {
+		t.Fatalf("after update: rating %v, want 6", g.AvgRating)
+	}
+
+	if err := s.RemoveReview(ctx, author, 1); err != nil {
+		t.Fatal(err)
+	}
+	g, _ = games.GetGameByID(ctx, 1)
+	if g.ReviewsCount != 2 || g.AvgRating != nil {
+		t.Fatalf("after removal: count %d, rating %v", g.ReviewsCount, g.AvgRating)
+	}
+}
+
+func TestOnlyOwnerOrModeratorChangesReview(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	addReviews(t, s, 5)
+
+	stranger := user.User{ID: 2, Role: auth.RoleUser}
+	if err := s.RemoveReview(ctx, stranger, 1); !errors.Is(err, ErrNotReviewOwner) {
+		t.Fatalf("stranger RemoveReview error = %v, want ErrNotReviewOwner", err)
+	}
+	moderator := user.User{ID: 3, Role: auth.RoleModerator}
+	if err := s.RemoveReview(ctx, moderator, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RemoveReview(ctx, moderator, 1); !errors.Is(err, ErrReviewNotFound) {
+		t.Fatalf("second RemoveReview error = %v, want ErrReviewNotFound", err)
+	}
+}
+
+func TestOneReviewPerUser(t *testing.T) {
+	s, _ := newTestService(t)
+	addReviews(t, s, 5)
+	err := s.AddReview(context.Background(), AddReviewRequest{GameID: 1, Rating: 7, User: user.User{ID: 1}})
+	if err == nil {
+		t.Fatal("second review by the same user was accepted")
+	}
+}
+
+func TestReviewsQueueSearchUpdates(t *testing.T) {
+	ctx := context.Background()
+	outbox := game.NewMemoryOutboxRepository()
+	genres, tags := genre.NewMemoryRepository(), tag.NewMemoryRepository()
+	games := game.NewMemoryRepository(outbox, genres, tags)
+	gameService := game.NewService(games, genres, tags, game.NewMemorySearchRepository(games))
+	if err := gameService.AddGame(ctx, game.AddGameRequest{Name: "Doom", ImageURL: "img.png", Genres: []string{"Shooter"}}); err != nil {
+		t.Fatal(err)
+	}
+	s := NewService(NewMemoryRepository(games), gameService)
+	addReviews(t, s, 8)
+
+	lag, err := outbox.GetLag(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// one event for adding the game, one for its new rating
+	if lag.Pending != 2 {
+		t.Fatalf("pending outbox events = %d, want 2", lag.Pending)
+	}
+}
@@ -0,0 +1,85 @@
+package router
+
+import (
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"igropoisk_backend/internal/auth"
+	"igropoisk_backend/internal/backup"
+	"igropoisk_backend/internal/config"
+	"igropoisk_backend/internal/game"
+	"igropoisk_backend/internal/logger"
+	"igropoisk_backend/internal/middleware"
+	"igropoisk_backend/internal/review"
+	"igropoisk_backend/internal/user"
+)
+
+// Services are everything the HTTP API is served from.
+type Services struct {
+	Tokens  auth.Service
+	Users   user.Service
+	Games   game.Service
+	Outbox  *game.OutboxDispatcher
+	Reviews review.Service
+	// Backup is nil when the storage cannot be backed up.
+	Backup backup.Service
+}
+
+// New builds the router of the public API.
+func New(s Services, corsCfg config.CORS) *gin.Engine {
+	userHandler := user.NewHandler(s.Users)
+	gameHandler := game.NewHandler(s.Games)
+	outboxHandler := game.NewOutboxHandler(s.Outbox)
+	reviewHandler := review.NewHandler(s.Reviews)
+
+	r := gin.New()
+	r.Use(logger.SlogMiddleware())
+	r.Use(gin.Recovery())
+	if len(corsCfg.AllowedOrigins) == 0 {
+		r.Use(cors.Default())
+	} else {
+		allowed := cors.DefaultConfig()
+		allowed.AllowOrigins = corsCfg.AllowedOrigins
+		allowed.AddAllowHeaders("Authorization")
+		r.Use(cors.New(allowed))
+	}
+
+	r.GET(".well-known/jwks.json", auth.HandleJWKS)
+
+	api := r.Group("api")
+	{
+		api.POST("register", userHandler.HandleRegistration)
+		api.POST("login", userHandler.HandleLogin)
+		api.POST("token/refresh", userHandler.HandleRefresh)
+		api.GET("games/:id/reviews", reviewHandler.GetReviewsByGameID)
+	}
+	authorizedApi := r.Group("api", middleware.AuthMiddleware(s.Tokens))
+	{
+		authorizedApi.POST("logout", userHandler.HandleLogout)
+
+		authorizedApi.GET("games/search", gameHandler.SearchGame)
+		authorizedApi.GET("games/suggest", gameHandler.SuggestGames)
+		authorizedApi.GET("games/:id", gameHandler.GetGameByID)
+		authorizedApi.GET("games/:id/similar", gameHandler.SimilarGames)
+		authorizedApi.GET("games", gameHandler.GetAllGames)
+		authorizedApi.POST("games", middleware.RequireRole(auth.RoleModerator), gameHandler.AddGame)
+		authorizedApi.PUT("games/:id", middleware.RequireRole(auth.RoleModerator), gameHandler.ReplaceGame)
+		authorizedApi.PATCH("games/:id", middleware.RequireRole(auth.RoleModerator), gameHandler.UpdateGame)
+		authorizedApi.DELETE("games/:id", middleware.RequireRole(auth.RoleAdmin), gameHandler.DeleteGameByID)
+		authorizedApi.POST("games/:id/tags", middleware.RequireRole(auth.RoleModerator), gameHandler.AddGameTags)
+		authorizedApi.DELETE("games/:id/tags/:tag", middleware.RequireRole(auth.RoleModerator), gameHandler.RemoveGameTag)
+		authorizedApi.GET("genres", gameHandler.ListGenres)
+		authorizedApi.GET("tags", gameHandler.ListTags)
+
+		authorizedApi.GET("admin/search/outbox", middleware.RequireRole(auth.RoleAdmin), outboxHandler.GetLag)
+		authorizedApi.POST("admin/search/reindex", middleware.RequireRole(auth.RoleAdmin), gameHandler.Reindex)
+		authorizedApi.POST("admin/games/import", middleware.RequireRole(auth.RoleAdmin), gameHandler.ImportGames)
+		if s.Backup != nil {
+			authorizedApi.GET("admin/export", middleware.RequireRole(auth.RoleAdmin), backup.NewHandler(s.Backup).Export)
+		}
+
+		authorizedApi.POST("games/:id/reviews", reviewHandler.AddReview)
+		authorizedApi.PUT("reviews/:id", reviewHandler.UpdateReview)
+		authorizedApi.DELETE("reviews/:id", reviewHandler.DeleteReview)
+	}
+	return r
+}
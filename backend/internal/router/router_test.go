@@ -0,0 +1,361 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"igropoisk_backend/internal/auth"
+	"igropoisk_backend/internal/config"
+	"igropoisk_backend/internal/game"
+	"igropoisk_backend/internal/game/genre"
+	"igropoisk_backend/internal/game/tag"
+	"igropoisk_backend/internal/review"
+	"igropoisk_backend/internal/user"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testAPI is the real router over in-memory repositories.
+type testAPI struct {
+	t      *testing.T
+	server *httptest.Server
+	users  user.Service
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	if err := auth.Init(auth.Options{SecretKey: "e2e-secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	outbox := game.NewMemoryOutboxRepository()
+	genres, tags := genre.NewMemoryRepository(), tag.NewMemoryRepository()
+	games := game.NewMemoryRepository(outbox, genres, tags)
+	search := game.NewMemorySearchRepository(games)
+	tokens := auth.NewService(auth.NewMemoryRepository())
+	users := user.NewService(user.NewMemoryRepository(), tokens)
+	gameService := game.NewService(games, genres, tags, search)
+
+	server := httptest.NewServer(New(Services{
+		Tokens:  tokens,
+		Users:   users,
+		Games:   gameService,
+		Outbox:  game.NewOutboxDispatcher(outbox, games, search),
+		Reviews: review.NewService(review.NewMemoryRepository(games), gameService),
+	}, config.CORS{}))
+	t.Cleanup(server.Close)
+	return &testAPI{t: t, server: server, users: users}
+}
+
+// do sends body as JSON, or as is when it is an io.Reader, and decodes a JSON response
+// into out when it is not nil.
+func (a *testAPI) do(method, path, token string, body, out any) int {
+	a.t.Helper()
+	reader, raw := body.(io.Reader)
+	if body != nil && !raw {
+		b, err := json.Marshal(body)
+		if err != nil {
+			a.t.Fatal(err)
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, a.server.URL+path, reader)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := a.server.Client().Do(req)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			a.t.Fatalf("%s %s: decoding response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func (a *testAPI) expect(want int, method, path, token string, body, out any) {
+	a.t.Helper()
+	if got := a.do(method, path, token, body, out); got != want {
+		a.t.Fatalf("%s %s: status %d, want %d", method, path, got, want)
+	}
+}
+
+func credentials(name string) map[string]string {
+	return map[string]string{"username": name, "password": "password123"}
+}
+
+// register signs name up with role and returns an access token carrying it; privileged
+// users are created the way operators create them.
+func (a *testAPI) register(name string, role auth.Role) string {
+	a.t.Helper()
+	var tokens auth.TokenPair
+	if role == auth.RoleUser {
+		a.expect(http.StatusOK, "POST", "/api/register", "", credentials(name), &tokens)
+		return tokens.AccessToken
+	}
+	if _, err := a.users.CreateUser(context.Background(), name, credentials(name)["password"], role); err != nil {
+		a.t.Fatal(err)
+	}
+	a.expect(http.StatusOK, "POST", "/api/login", "", credentials(name), &tokens)
+	return tokens.AccessToken
+}
+
+func (a *testAPI) addGame(token, name, description, genreName string) game.Game {
+	a.t.Helper()
+	body := game.AddGameRequest{Name: name, Description: description, ImageURL: "https://img/" + name, Genres: []string{genreName}}
+	a.expect(http.StatusCreated, "POST", "/api/games", token, body, nil)
+	var page game.Page
+	a.expect(http.StatusOK, "GET", "/api/games?sort=newest&limit=1", token, nil, &page)
+	return page.Games[0]
+}
+
+func TestRegistrationAndLogin(t *testing.T) {
+	api := newTestAPI(t)
+
+	var tokens auth.TokenPair
+	api.expect(http.StatusOK, "POST", "/api/register", "", credentials("alice"), &tokens)
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("register returned %+v", tokens)
+	}
+	api.expect(http.StatusBadRequest, "POST", "/api/register", "", map[string]string{"username": "al", "password": "password123"}, nil)
+	if status := api.do("POST", "/api/register", "", credentials("alice"), nil); status < 400 {
+		t.Fatalf("registering a taken name: status %d", status)
+	}
+
+	api.expect(http.StatusOK, "POST", "/api/login", "", credentials("alice"), &tokens)
+	if status := api.do("POST", "/api/login", "", map[string]string{"username": "alice", "password": "wrong-password"}, nil); status < 400 {
+		t.Fatalf("login with a wrong password: status %d", status)
+	}
+
+	var refreshed auth.TokenPair
+	api.expect(http.StatusOK, "POST", "/api/token/refresh", "", map[string]string{"refresh_token": tokens.RefreshToken}, &refreshed)
+	api.expect(http.StatusOK, "GET", "/api/games", refreshed.AccessToken, nil, nil)
+}
+
+func TestAuthFailures(t *testing.T) {
+	api := newTestAPI(t)
+	token := api.register("alice", auth.RoleUser)
+
+	api.expect(http.StatusUnauthorized, "GET", "/api/games", "", nil, nil)
+	api.expect(http.StatusUnauthorized, "GET", "/api/games", "not-a-jwt", nil, nil)
+
+	req, _ := http.NewRequest("GET", api.server.URL+"/api/games", nil)
+	req.Header.Set("Authorization", "Token "+token)
+	resp, err := api.server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("non-bearer scheme: status %d", resp.StatusCode)
+	}
+
+	// a token signed with another secret
+	if err := auth.Init(auth.Options{SecretKey: "another-secret"}); err != nil {
+		t.Fatal(err)
+	}
+	forged, err := auth.GenerateToken(1, "alice", auth.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.Init(auth.Options{SecretKey: "e2e-secret"}); err != nil {
+		t.Fatal(err)
+	}
+	api.expect(http.StatusUnauthorized, "GET", "/api/games", forged, nil, nil)
+
+	var tokens auth.TokenPair
+	api.expect(http.StatusOK, "POST", "/api/login", "", credentials("alice"), &tokens)
+	api.expect(http.StatusNoContent, "POST", "/api/logout", tokens.AccessToken, map[string]string{"refresh_token": tokens.RefreshToken}, nil)
+	api.expect(http.StatusUnauthorized, "GET", "/api/games", tokens.AccessToken, nil, nil)
+}
+
+func TestGameCRUD(t *testing.T) {
+	api := newTestAPI(t)
+	player := api.register("player", auth.RoleUser)
+	moderator := api.register("moderator", auth.RoleModerator)
+	admin := api.register("admin", auth.RoleAdmin)
+
+	body := game.AddGameRequest{Name: "Doom", Description: "Shooter", ImageURL: "https://img/doom", Genres: []string{"FPS"}}
+	api.expect(http.StatusForbidden, "POST", "/api/games", player, body, nil)
+	doom := api.addGame(moderator, "Doom", "Shooter", "FPS")
+
+	var got game.Game
+	api.expect(http.StatusOK, "GET", "/api/games/"+strconv.Itoa(doom.ID), player, nil, &got)
+	if got.Name != "Doom" || len(got.Genres) != 1 || got.Genres[0].Name != "FPS" {
+		t.Fatalf("GET game = %+v", got)
+	}
+
+	api.expect(http.StatusOK, "PATCH", "/api/games/"+strconv.Itoa(doom.ID), moderator, map[string]string{"description": "Demons"}, &got)
+	if got.Description != "Demons" || got.Name != "Doom" {
+		t.Fatalf("PATCH game = %+v", got)
+	}
+	api.expect(http.StatusBadRequest, "PUT", "/api/games/"+strconv.Itoa(doom.ID), moderator, map[string]string{"name": "Doom"}, nil)
+	api.expect(http.StatusNotFound, "PATCH", "/api/games/999", moderator, map[string]string{"description": "x"}, nil)
+
+	api.expect(http.StatusForbidden, "DELETE", "/api/games/"+strconv.Itoa(doom.ID), moderator, nil, nil)
+	api.expect(http.StatusNoContent, "DELETE", "/api/games/"+strconv.Itoa(doom.ID), admin, nil, nil)
+	api.expect(http.StatusNotFound, "GET", "/api/games/"+strconv.Itoa(doom.ID), player, nil, nil)
+}
+
+func TestSearch(t *testing.T) {
+	api := newTestAPI(t)
+	moderator := api.register("moderator", auth.RoleModerator)
+	api.addGame(moderator, "Doom", "Fast shooter against demons", "FPS")
+	api.addGame(moderator, "Quake", "Arena shooter", "FPS")
+	api.addGame(moderator, "Skyrim", "Open world RPG with dragons", "RPG")
+
+	var result game.SearchResult
+	api.expect(http.StatusOK, "GET", "/api/games/search?query=shooter", moderator, nil, &result)
+	if result.Total != 2 {
+		t.Fatalf("search total = %d, want 2", result.Total)
+	}
+	api.expect(http.StatusOK, "GET", "/api/games/search?query=dragons", moderator, nil, &result)
+	if result.Total != 1 || result.Hits[0].Game.Name != "Skyrim" {
+		t.Fatalf("search hits = %+v", result.Hits)
+	}
+	api.expect(http.StatusBadRequest, "GET", "/api/games/search", moderator, nil, nil)
+	// Elasticsearch refuses pages past index.max_result_window
+	api.expect(http.StatusOK, "GET", "/api/games/search?query=shooter&offset=9900&limit=100", moderator, nil, nil)
+	api.expect(http.StatusBadRequest, "GET", "/api/games/search?query=shooter&offset=9990", moderator, nil, nil)
+
+	var suggest struct {
+		Suggestions []game.Suggestion `json:"suggestions"`
+	}
+	api.expect(http.StatusOK, "GET", "/api/games/suggest?prefix=qu", moderator, nil, &suggest)
+	if len(suggest.Suggestions) != 1 || suggest.Suggestions[0].Name != "Quake" {
+		t.Fatalf("suggestions = %+v", suggest.Suggestions)
+	}
+}
+
+func TestReviewsAggregateRating(t *testing.T) {
+	api := newTestAPI(t)
+	moderator := api.register("moderator", auth.RoleModerator)
+	doom := api.addGame(moderator, "Doom", "Shooter", "FPS")
+	path := "/api/games/" + strconv.Itoa(doom.ID)
+
+	first := api.register("first", auth.RoleUser)
+	api.expect(http.StatusCreated, "POST", path+"/reviews", first, map[string]any{"rating": 6, "content": "ok"}, nil)
+	api.expect(http.StatusBadRequest, "POST", path+"/reviews", first, map[string]any{"rating": 9, "content": "again"}, nil)
+	api.expect(http.StatusBadRequest, "POST", path+"/reviews", first, map[string]any{"rating": 11}, nil)
+
+	api.expect(http.StatusCreated, "POST", path+"/reviews", api.register("second", auth.RoleUser), map[string]any{"rating": 8}, nil)
+	var got game.Game
+	api.expect(http.StatusOK, "GET", path, first, nil, &got)
+	if got.ReviewsCount != 2 || got.AvgRating != nil {
+		t.Fatalf("after two reviews: count %d, rating %v", got.ReviewsCount, got.AvgRating)
+	}
+
+	api.expect(http.StatusCreated, "POST", path+"/reviews", api.register("third", auth.RoleUser), map[string]any{"rating": 10}, nil)
+	api.expect(http.StatusOK, "GET", path, first, nil, &got)
+	if got.ReviewsCount != 3 || got.AvgRating == nil || *got.AvgRating != 8 {
+		t.Fatalf("after three reviews: count %d, rating %v", got.ReviewsCount, got.AvgRating)
+	}
+
+	var reviews struct {
+		Reviews []review.Review `json:"reviews"`
+	}
+	api.expect(http.StatusOK, "GET", path+"/reviews", "", nil, &reviews)
+	if len(reviews.Reviews) != 3 {
+		t.Fatalf("got %d reviews, want 3", len(reviews.Reviews))
+	}
+}
+
+func TestImportGames(t *testing.T) {
+	api := newTestAPI(t)
+	moderator := api.register("moderator", auth.RoleModerator)
+	admin := api.register("admin", auth.RoleAdmin)
+	api.addGame(moderator, "Doom", "Shooter", "FPS")
+
+	csv := "name,description,image_url,genres,tags\n" +
+		"Doom,Again,https://img/doom,FPS,\n" +
+		"Quake,Arena shooter,https://img/quake,FPS;Arena,Multiplayer\n" +
+		"Hades,,https://img/hades,Roguelike,\n"
+	api.expect(http.StatusForbidden, "POST", "/api/admin/games/import?format=csv", moderator, strings.NewReader(csv), nil)
+	api.expect(http.StatusBadRequest, "POST", "/api/admin/games/import?format=xml", admin, strings.NewReader(csv), nil)
+
+	var report game.ImportReport
+	api.expect(http.StatusOK, "POST", "/api/admin/games/import?format=csv", admin, strings.NewReader(csv), &report)
+	if report.Created != 1 || report.Skipped != 1 || report.Failed != 1 {
+		t.Fatalf("report = %+v, want 1 created, 1 skipped, 1 failed", report)
+	}
+
+	var got game.Game
+	api.expect(http.StatusOK, "GET", "/api/games/"+strconv.Itoa(report.Rows[1].ID), admin, nil, &got)
+	if got.Name != "Quake" || len(got.Genres) != 2 || len(got.Tags) != 1 || got.Tags[0].Name != "multiplayer" {
+		t.Fatalf("imported game = %+v", got)
+	}
+}
+
+func TestGameTags(t *testing.T) {
+	api := newTestAPI(t)
+	player := api.register("player", auth.RoleUser)
+	moderator := api.register("moderator", auth.RoleModerator)
+	doom := api.addGame(moderator, "Doom", "Shooter", "FPS")
+	api.addGame(moderator, "Quake", "Arena shooter", "FPS")
+	tagsPath := "/api/games/" + strconv.Itoa(doom.ID) + "/tags"
+
+	body := game.AddGameTagsRequest{Tags: []string{"Fast Paced", "gore"}}
+	api.expect(http.StatusForbidden, "POST", tagsPath, player, body, nil)
+	api.expect(http.StatusBadRequest, "POST", tagsPath, moderator, game.AddGameTagsRequest{}, nil)
+	api.expect(http.StatusNotFound, "POST", "/api/games/42/tags", moderator, body, nil)
+	var got game.Game
+	api.expect(http.StatusOK, "POST", tagsPath, moderator, body, &got)
+	if len(got.Tags) != 2 || got.Tags[0].Name != "fast paced" {
+		t.Fatalf("tagged game = %+v", got)
+	}
+
+	var page game.Page
+	api.expect(http.StatusOK, "GET", "/api/games?tag=gore", player, nil, &page)
+	if page.Total != 1 || page.Games[0].ID != doom.ID {
+		t.Fatalf("games tagged gore = %+v", page.Games)
+	}
+	// filters are normalized like stored tags, repeats included
+	api.expect(http.StatusOK, "GET", "/api/games?tag=Fast%20%20Paced&tag=GORE&tag=gore", player, nil, &page)
+	if page.Total != 1 || page.Games[0].ID != doom.ID {
+		t.Fatalf("games tagged Fast  Paced and GORE = %+v", page.Games)
+	}
+	var result game.SearchResult
+	api.expect(http.StatusOK, "GET", "/api/games/search?query=shooter&tag=Gore&tag=gore", player, nil, &result)
+	if result.Total != 1 || result.Hits[0].Game.ID != doom.ID {
+		t.Fatalf("search tagged Gore = %+v", result.Hits)
+	}
+	api.expect(http.StatusBadRequest, "GET", "/api/games?tag=%20", player, nil, nil)
+	var tags struct {
+		Tags []tag.Tag `json:"tags"`
+	}
+	api.expect(http.StatusOK, "GET", "/api/tags", player, nil, &tags)
+	if len(tags.Tags) != 2 {
+		t.Fatalf("GET tags = %+v", tags)
+	}
+	var genres struct {
+		Genres []genre.Genre `json:"genres"`
+	}
+	api.expect(http.StatusOK, "GET", "/api/genres", player, nil, &genres)
+	if len(genres.Genres) != 1 || genres.Genres[0].Name != "FPS" {
+		t.Fatalf("GET genres = %+v", genres)
+	}
+
+	api.expect(http.StatusOK, "DELETE", tagsPath+"/gore", moderator, nil, &got)
+	if len(got.Tags) != 1 {
+		t.Fatalf("game after removing a tag = %+v", got)
+	}
+
+	replace := game.UpdateGameRequest{Name: &doom.Name, Description: &doom.Description, ImageURL: &doom.ImageURL, Genres: &[]string{"FPS"}}
+	api.expect(http.StatusOK, "PUT", "/api/games/"+strconv.Itoa(doom.ID), moderator, replace, &got)
+	if len(got.Tags) != 0 {
+		t.Fatalf("game after PUT without tags = %+v", got)
+	}
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"igropoisk_backend/internal/auth"
+	"igropoisk_backend/internal/backup"
+	"igropoisk_backend/internal/config"
+	"igropoisk_backend/internal/db/elastic"
+	"igropoisk_backend/internal/db/migrate"
+	"igropoisk_backend/internal/db/postgres"
+	"igropoisk_backend/internal/game"
+	"igropoisk_backend/internal/game/genre"
+	"igropoisk_backend/internal/game/tag"
+	"igropoisk_backend/internal/logger"
+	"igropoisk_backend/internal/review"
+	"igropoisk_backend/internal/router"
+	"igropoisk_backend/internal/user"
+	"igropoisk_backend/migrations"
+)
+
+// App is the wired application without the HTTP server: repositories and the services
+// built on them. The admin commands share it with the Server.
+type App struct {
+	cfg      *config.Config
+	pool     *pgxpool.Pool
+	Services router.Services
+	GameRepo game.Repository
+
+	searchRepo     game.SearchRepository
+	searchFallback *game.FallbackSearchRepository
+}
+
+// NewApp connects to the configured backends; call Close when done.
+func NewApp(ctx context.Context, cfg *config.Config) (*App, error) {
+	a := &App{cfg: cfg}
+	var (
+		tokenRepo  auth.Repository
+		userRepo   user.Repository
+		genreRepo  genre.Repository
+		tagRepo    tag.Repository
+		outboxRepo game.OutboxRepository
+		reviewRepo review.Repository
+	)
+	switch cfg.Storage {
+	case "memory":
+		memoryOutbox := game.NewMemoryOutboxRepository()
+		genreRepo = genre.NewMemoryRepository()
+		tagRepo = tag.NewMemoryRepository()
+		memoryGames := game.NewMemoryRepository(memoryOutbox, genreRepo, tagRepo)
+		tokenRepo = auth.NewMemoryRepository()
+		userRepo = user.NewMemoryRepository()
+		a.GameRepo = memoryGames
+		a.searchRepo = game.NewMemorySearchRepository(memoryGames)
+		outboxRepo = memoryOutbox
+		reviewRepo = review.NewMemoryRepository(memoryGames)
+	case "postgres":
+		pool, err := postgres.NewPool(ctx, PostgresOptions(cfg.Postgres))
+		if err != nil {
+			return nil, err
+		}
+		a.pool = pool
+		if cfg.Features.MigrateOnStart {
+			if err := a.Migrate(ctx); err != nil {
+				a.Close()
+				return nil, err
+			}
+		}
+		tokenRepo = auth.NewPostgresRepository(pool)
+		userRepo = user.NewPostgresRepository(pool)
+		a.GameRepo = game.NewPostgresRepository(pool)
+		genreRepo = genre.NewPostgresRepository(pool)
+		tagRepo = tag.NewPostgresRepository(pool)
+		outboxRepo = game.NewPostgresOutboxRepository(pool)
+		reviewRepo = review.NewPostgresRepository(pool)
+		if err := a.initSearch(outboxRepo); err != nil {
+			a.Close()
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", cfg.Storage)
+	}
+
+	tokenService := auth.NewService(tokenRepo)
+	gameService := game.NewService(a.GameRepo, genreRepo, tagRepo, a.searchRepo)
+	a.Services = router.Services{
+		Tokens:  tokenService,
+		Users:   user.NewService(userRepo, tokenService),
+		Games:   gameService,
+		Outbox:  game.NewOutboxDispatcher(outboxRepo, a.GameRepo, a.searchRepo),
+		Reviews: review.NewService(reviewRepo, gameService),
+	}
+	if a.pool != nil {
+		a.Services.Backup = backup.NewService(backup.NewPostgresStore(a.pool), gameService)
+	}
+	return a, nil
+}
+
+// Migrate applies the pending migrations.
+func (a *App) Migrate(ctx context.Context) error {
+	if a.pool == nil {
+		return nil
+	}
+	migrator, err := migrate.New(a.pool, migrations.FS)
+	if err != nil {
+		return err
+	}
+	ran, err := migrator.Up(ctx)
+	for _, m := range ran {
+		logger.Logger.Info("Applied migration",
+			"version", m.Version,
+			"name", m.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to migrate: %w", err)
+	}
+	return nil
+}
+
+func (a *App) initSearch(outbox game.OutboxRepository) error {
+	switch a.cfg.Search.Backend {
+	case "postgres":
+		a.searchRepo = game.NewPostgresSearchRepository(a.pool)
+	case "elastic":
+		searchOpts := game.ElasticOptions{Outbox: outbox}
+		if a.cfg.Search.SynonymsFile != "" {
+			synonyms, err := game.LoadSynonyms(a.cfg.Search.SynonymsFile)
+			if err != nil {
+				return fmt.Errorf("failed to load search synonyms: %w", err)
+			}
+			searchOpts.Synonyms = synonyms
+		}
+		client, err := elastic.NewClient(a.cfg.Elastic.URL)
+		if err != nil {
+			return err
+		}
+		a.searchRepo = game.NewElasticRepository(client, searchOpts)
+		if a.cfg.Search.Fallback == "postgres" {
+			a.searchFallback = game.NewFallbackSearchRepository(a.searchRepo, game.NewPostgresSearchRepository(a.pool))
+			a.searchRepo = a.searchFallback
+		}
+	default:
+		return fmt.Errorf("unknown SEARCH_BACKEND %q", a.cfg.Search.Backend)
+	}
+	return nil
+}
+
+// Close releases the database pool.
+func (a *App) Close() {
+	if a.pool != nil {
+		a.pool.Close()
+	}
+}
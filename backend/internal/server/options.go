@@ -0,0 +1,27 @@
+package server
+
+import (
+	"igropoisk_backend/internal/auth"
+	"igropoisk_backend/internal/config"
+	"igropoisk_backend/internal/db/postgres"
+	"igropoisk_backend/internal/logger"
+)
+
+// The low-level packages take their own options, so they do not depend on config.
+
+func AuthOptions(cfg config.Auth) auth.Options {
+	return auth.Options{SecretKey: cfg.SecretKey, KeysDir: cfg.KeysDir, SigningKeyID: cfg.SigningKeyID}
+}
+
+func LogOptions(cfg config.Log) logger.Options {
+	return logger.Options{File: cfg.File, Level: cfg.Level, Format: cfg.Format}
+}
+
+func PostgresOptions(cfg config.Postgres) postgres.Options {
+	return postgres.Options{
+		URL:            cfg.URL,
+		MaxConns:       cfg.MaxConns,
+		MinConns:       cfg.MinConns,
+		ConnectTimeout: cfg.ConnectTimeout,
+	}
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"igropoisk_backend/internal/auth"
+	"igropoisk_backend/internal/config"
+	"igropoisk_backend/internal/logger"
+	"igropoisk_backend/internal/router"
+	"net/http"
+	"sync"
+)
+
+// Server is the App plus its background workers and the HTTP server in front of it.
+type Server struct {
+	app  *App
+	http *http.Server
+}
+
+// New builds every dependency; call Close if it is never Run.
+func New(ctx context.Context, cfg *config.Config) (*Server, error) {
+	app, err := NewApp(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{app: app}
+
+	// memory storage starts empty, so there would be no way to get an admin
+	if cfg.Storage == "memory" && cfg.Admin.Name != "" {
+		if _, err := app.Services.Users.CreateUser(ctx, cfg.Admin.Name, cfg.Admin.Password, auth.RoleAdmin); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to create the admin user: %w", err)
+		}
+	}
+
+	s.http = &http.Server{
+		Addr:         ":" + cfg.HTTP.Port,
+		Handler:      router.New(app.Services, cfg.CORS),
+		ReadTimeout:  cfg.HTTP.ReadTimeout,
+		WriteTimeout: cfg.HTTP.WriteTimeout,
+		IdleTimeout:  cfg.HTTP.IdleTimeout,
+	}
+	return s, nil
+}
+
+// Handler is the HTTP API, for tests that do not need a listener.
+func (s *Server) Handler() http.Handler {
+	return s.http.Handler
+}
+
+// Run syncs the search index, starts the background workers and serves HTTP until ctx
+// is cancelled. It then stops accepting connections, waits up to ShutdownTimeout for
+// in-flight requests, stops the workers and closes the database pool and log file.
+func (s *Server) Run(ctx context.Context) error {
+	defer s.Close()
+	cfg := s.app.cfg
+
+	if cfg.Features.ReindexOnStart {
+		if _, err := s.app.searchRepo.Reindex(ctx, s.app.GameRepo); err != nil {
+			logger.Logger.Error("Unable to sync elastic with TS",
+				"error", err)
+		}
+	}
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	startWorker := func(run func(context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workersCtx)
+		}()
+	}
+	startWorker(s.app.Services.Tokens.RunCleanup)
+	if cfg.Features.OutboxDispatcher {
+		startWorker(s.app.Services.Outbox.Run)
+	}
+	if s.app.searchFallback != nil {
+		startWorker(s.app.searchFallback.Run)
+	}
+	defer func() {
+		stopWorkers()
+		workers.Wait()
+	}()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Logger.Info("Listening", "addr", s.http.Addr)
+		serveErr <- s.http.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Logger.Info("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+	if err := s.http.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to drain connections: %w", err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Close releases the database pool and the log file.
+func (s *Server) Close() {
+	s.app.Close()
+	logger.CloseFile()
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"igropoisk_backend/internal/auth"
+	"igropoisk_backend/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Storage = "memory"
+	cfg.HTTP.Port = "0"
+	cfg.HTTP.ShutdownTimeout = time.Second
+	cfg.Auth.SecretKey = "test-secret"
+	cfg.Admin = config.Admin{Name: "admin", Password: "password123"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.Init(AuthOptions(cfg.Auth)); err != nil {
+		t.Fatal(err)
+	}
+	return &cfg
+}
+
+func TestNewServesAPI(t *testing.T) {
+	srv, err := New(context.Background(), testConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/api/games", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestRunStopsOnCancel(t *testing.T) {
+	srv, err := New(context.Background(), testConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(ctx) }()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
+
+func TestNewRejectsUnknownStorage(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Storage = "sqlite"
+	if _, err := New(context.Background(), cfg); err == nil {
+		t.Fatal("expected an error for an unknown storage backend")
+	}
+}
@@ -3,6 +3,8 @@ package user
 import (
 	"errors"
 	"github.com/gin-gonic/gin"
+	"igropoisk_backend/internal/auth"
+	"igropoisk_backend/internal/middleware"
 	"net/http"
 )
 
@@ -31,13 +33,13 @@ func (r *Handler) HandleRegistration(c *gin.Context) {
 		return
 	}
 
-	token, err := r.service.Register(c.Request.Context(), req.Username, req.Password)
+	tokens, err := r.service.Register(c.Request.Context(), req.Username, req.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	c.JSON(http.StatusOK, tokens)
 }
 func validateRequest(req request) error {
 	if len(req.Username) < 3 || len(req.Username) > 32 {
@@ -61,12 +63,63 @@ func (r *Handler) HandleLogin(c *gin.Context) {
 		return
 	}
 
-	token, err := r.service.Login(c.Request.Context(), req.Username, req.Password)
+	tokens, err := r.service.Login(c.Request.Context(), req.Username, req.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	c.JSON(http.StatusOK, tokens)
 
 }
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (r *Handler) HandleRefresh(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+		return
+	}
+
+	tokens, err := r.service.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) || errors.Is(err, auth.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// HandleLogout revokes the caller's access token and, if given, the refresh token family it belongs to.
+func (r *Handler) HandleLogout(c *gin.Context) {
+	var req refreshRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+	claims, ok := c.Request.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "claims not found"})
+		return
+	}
+
+	if err := r.service.Logout(c.Request.Context(), claims, req.RefreshToken); err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
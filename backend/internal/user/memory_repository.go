@@ -0,0 +1,55 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"igropoisk_backend/internal/auth"
+	"sync"
+)
+
+// MemoryRepository keeps users in memory for tests and local development.
+type MemoryRepository struct {
+	mu    sync.RWMutex
+	users []User
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{}
+}
+
+func (m *MemoryRepository) AddUser(ctx context.Context, name, passwordHash string, role auth.Role) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if u.Name == name {
+			return nil, fmt.Errorf("AddUser : user %q already exists", name)
+		}
+	}
+	u := User{ID: len(m.users) + 1, Name: name, Role: role, PasswordHash: passwordHash}
+	m.users = append(m.users, u)
+	u.PasswordHash = ""
+	return &u, nil
+}
+
+func (m *MemoryRepository) GetUserByID(ctx context.Context, id int) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if id <= 0 || id > len(m.users) {
+		return nil, fmt.Errorf("GetUserByID : %w", pgx.ErrNoRows)
+	}
+	u := m.users[id-1]
+	u.PasswordHash = ""
+	return &u, nil
+}
+
+func (m *MemoryRepository) GetUserByName(ctx context.Context, name string) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, u := range m.users {
+		if u.Name == name {
+			return &u, nil
+		}
+	}
+	return nil, fmt.Errorf("GetUserByName : %w", pgx.ErrNoRows)
+}
@@ -1 +1 @@
-INSERT INTO users (name, password_hash) VALUES ($1, $2) RETURNING id, name
\ No newline at end of file
+INSERT INTO users (name, password_hash, role) VALUES ($1, $2, $3) RETURNING id, name, role
\ No newline at end of file
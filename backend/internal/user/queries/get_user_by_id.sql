@@ -1 +1 @@
-SELECT id,name FROM USERS WHERE ID = $1
\ No newline at end of file
+SELECT id,name,role FROM USERS WHERE ID = $1
\ No newline at end of file
@@ -1 +1 @@
-SELECT id, name, password_hash FROM users WHERE name = $1
\ No newline at end of file
+SELECT id, name, role, password_hash FROM users WHERE name = $1
\ No newline at end of file
@@ -5,6 +5,7 @@ import (
 	_ "embed"
 	"fmt"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"igropoisk_backend/internal/auth"
 )
 
 //go:embed queries/add_user.sql
@@ -17,7 +18,7 @@ var getUserByIDSQL string
 var getUserByNameSQL string
 
 type Repository interface {
-	AddUser(ctx context.Context, name, passwordHash string) (*User, error)
+	AddUser(ctx context.Context, name, passwordHash string, role auth.Role) (*User, error)
 	GetUserByID(ctx context.Context, id int) (*User, error)
 	GetUserByName(ctx context.Context, name string) (*User, error)
 }
@@ -30,9 +31,9 @@ func NewPostgresRepository(pool *pgxpool.Pool) Repository {
 	return &PostgresRepository{pool: pool}
 }
 
-func (p *PostgresRepository) AddUser(ctx context.Context, name, passwordHash string) (*User, error) {
+func (p *PostgresRepository) AddUser(ctx context.Context, name, passwordHash string, role auth.Role) (*User, error) {
 	user := User{}
-	err := p.pool.QueryRow(ctx, addUserSQL, name, passwordHash).Scan(&user.ID, &user.Name)
+	err := p.pool.QueryRow(ctx, addUserSQL, name, passwordHash, role).Scan(&user.ID, &user.Name, &user.Role)
 	if err != nil {
 		return nil, fmt.Errorf("AddUser : %w", err)
 	}
@@ -41,7 +42,7 @@ func (p *PostgresRepository) AddUser(ctx context.Context, name, passwordHash str
 
 func (p *PostgresRepository) GetUserByID(ctx context.Context, id int) (*User, error) {
 	user := &User{}
-	err := p.pool.QueryRow(ctx, getUserByIDSQL, id).Scan(&user.ID, &user.Name)
+	err := p.pool.QueryRow(ctx, getUserByIDSQL, id).Scan(&user.ID, &user.Name, &user.Role)
 	if err != nil {
 		return nil, fmt.Errorf("GetUserByID : %w", err)
 	}
@@ -50,7 +51,7 @@ func (p *PostgresRepository) GetUserByID(ctx context.Context, id int) (*User, er
 
 func (p *PostgresRepository) GetUserByName(ctx context.Context, name string) (*User, error) {
 	user := &User{}
-	err := p.pool.QueryRow(ctx, getUserByNameSQL, name).Scan(&user.ID, &user.Name, &user.PasswordHash)
+	err := p.pool.QueryRow(ctx, getUserByNameSQL, name).Scan(&user.ID, &user.Name, &user.Role, &user.PasswordHash)
 	if err != nil {
 		return nil, fmt.Errorf("GetUserByName : %w", err)
 	}
@@ -3,71 +3,138 @@ package user
 import (
 	"context"
 	"errors"
+	"fmt"
 	"golang.org/x/crypto/bcrypt"
 	"igropoisk_backend/internal/auth"
 	"igropoisk_backend/internal/logger"
 )
 
 type Service interface {
-	Register(ctx context.Context, name, password string) (token string, err error)
-	Login(ctx context.Context, name, password string) (token string, err error)
+	Register(ctx context.Context, name, password string) (*auth.TokenPair, error)
+	Login(ctx context.Context, name, password string) (*auth.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*auth.TokenPair, error)
+	Logout(ctx context.Context, claims *auth.Claims, refreshToken string) error
+	// CreateUser adds a user with role and no session, for operators.
+	CreateUser(ctx context.Context, name, password string, role auth.Role) (*User, error)
 }
 
 type service struct {
-	repo Repository
+	repo   Repository
+	tokens auth.Service
 }
 
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+func NewService(repo Repository, tokens auth.Service) Service {
+	return &service{repo: repo, tokens: tokens}
 }
 
-func (s *service) Register(ctx context.Context, name, password string) (token string, err error) {
+func (s *service) addUser(ctx context.Context, name, password string, role auth.Role) (*User, error) {
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		logger.Logger.Error("Failed to hash password",
 			"username", name,
 			"error", err)
-		return "", errors.New("failed to hash password")
+		return nil, errors.New("failed to hash password")
 	}
-	user, err := s.repo.AddUser(ctx, name, string(passwordHash))
+	user, err := s.repo.AddUser(ctx, name, string(passwordHash), role)
 	if err != nil {
 		logger.Logger.Error("Failed to add user",
 			"username", name,
 			"error", err)
-		return "", errors.New("failed to add user")
+		return nil, errors.New("failed to add user")
 	}
-	token, err = auth.GenerateToken(user.ID, user.Name)
+	return user, nil
+}
+
+func (s *service) Register(ctx context.Context, name, password string) (*auth.TokenPair, error) {
+	user, err := s.addUser(ctx, name, password, auth.RoleUser)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := s.tokens.IssueTokens(ctx, user.ID, user.Name, user.Role, "")
 	if err != nil {
 		logger.Logger.Error("Failed to generate token",
 			"username", name,
 			"error", err)
-		return "", errors.New("failed to generate token")
+		return nil, errors.New("failed to generate token")
 	}
-	return token, err
+	return tokens, nil
 }
 
-func (s *service) Login(ctx context.Context, name, password string) (token string, err error) {
+func (s *service) Login(ctx context.Context, name, password string) (*auth.TokenPair, error) {
 	user, err := s.repo.GetUserByName(ctx, name)
 	if err != nil {
 		logger.Logger.Error("Failed to get user by name",
 			"username", name,
 			"error", err)
-		return "", errors.New("failed to get user by name")
+		return nil, errors.New("failed to get user by name")
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
 		logger.Logger.Error("Invalid password",
 			"username", name,
 			"error", err)
-		return "", errors.New("invalid username or password")
+		return nil, errors.New("invalid username or password")
 	}
 
-	token, err = auth.GenerateToken(user.ID, user.Name)
+	tokens, err := s.tokens.IssueTokens(ctx, user.ID, user.Name, user.Role, "")
 	if err != nil {
 		logger.Logger.Error("Failed to generate token",
 			"username", name,
 			"error", err)
-		return "", errors.New("failed to generate token")
+		return nil, errors.New("failed to generate token")
+	}
+	return tokens, nil
+}
+
+func (s *service) Refresh(ctx context.Context, refreshToken string) (*auth.TokenPair, error) {
+	old, err := s.tokens.ConsumeRefreshToken(ctx, refreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) || errors.Is(err, auth.ErrRefreshTokenReused) {
+			return nil, err
+		}
+		logger.Logger.Error("Failed to consume refresh token",
+			"error", err)
+		return nil, errors.New("failed to refresh token")
+	}
+	// re-read the user so that role changes apply on the next refresh
+	user, err := s.repo.GetUserByID(ctx, old.UserID)
+	if err != nil {
+		logger.Logger.Error("Failed to get user by id",
+			"user_id", old.UserID,
+			"error", err)
+		return nil, errors.New("failed to refresh token")
+	}
+	tokens, err := s.tokens.IssueTokens(ctx, user.ID, user.Name, user.Role, old.FamilyID)
+	if err != nil {
+		logger.Logger.Error("Failed to generate token",
+			"user_id", user.ID,
+			"error", err)
+		return nil, errors.New("failed to generate token")
+	}
+	return tokens, nil
+}
+
+func (s *service) Logout(ctx context.Context, claims *auth.Claims, refreshToken string) error {
+	err := s.tokens.Logout(ctx, claims, refreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			return err
+		}
+		logger.Logger.Error("Failed to log out",
+			"user_id", claims.UserID,
+			"error", err)
+		return errors.New("failed to log out")
+	}
+	return nil
+}
+
+func (s *service) CreateUser(ctx context.Context, name, password string, role auth.Role) (*User, error) {
+	if err := validateRequest(request{Username: name, Password: password}); err != nil {
+		return nil, err
+	}
+	if !role.Valid() {
+		return nil, fmt.Errorf("unknown role %q", role)
 	}
-	return token, nil
+	// the role goes in with the user, so a failure leaves no half-made account behind
+	return s.addUser(ctx, name, password, role)
 }
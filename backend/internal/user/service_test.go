@@ -0,0 +1,106 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"igropoisk_backend/internal/auth"
+	"testing"
+)
+
+func newTestService(t *testing.T) Service {
+	t.Helper()
+	if err := auth.Init(auth.Options{SecretKey: "test-secret"}); err != nil {
+		t.Fatal(err)
+	}
+	return NewService(NewMemoryRepository(), auth.NewService(auth.NewMemoryRepository()))
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	tokens, err := s.Register(ctx, "alice", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := auth.ParseToken(tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Username != "alice" || claims.Role != auth.RoleUser {
+		t.Fatalf("claims = %+v", claims)
+	}
+
+	if _, err := s.Register(ctx, "alice", "other"); err == nil {
+		t.Fatal("registered the same name twice")
+	}
+	if _, err := s.Login(ctx, "alice", "wrong"); err == nil {
+		t.Fatal("logged in with a wrong password")
+	}
+	if _, err := s.Login(ctx, "alice", "password"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRefreshRotatesAndDetectsReuse(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	first, err := s.Register(ctx, "bob", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := s.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Refresh(ctx, first.RefreshToken); !errors.Is(err, auth.ErrRefreshTokenReused) {
+		t.Fatalf("reusing a refresh token: error = %v, want ErrRefreshTokenReused", err)
+	}
+	// reuse revokes the whole family, including the rotated token
+	if _, err := s.Refresh(ctx, second.RefreshToken); err == nil {
+		t.Fatal("refresh token of a compromised family was accepted")
+	}
+}
+
+func TestLogoutRevokesAccessToken(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	tokens, err := s.Register(ctx, "carol", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := auth.ParseToken(tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Logout(ctx, claims, tokens.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Refresh(ctx, tokens.RefreshToken); err == nil {
+		t.Fatal("refresh token still works after logout")
+	}
+}
+
+func TestCreateUserWithRole(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	if _, err := s.CreateUser(ctx, "root", "password", auth.Role("owner")); err == nil {
+		t.Fatal("created a user with an unknown role")
+	}
+	if _, err := s.CreateUser(ctx, "root", "password", auth.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := s.Login(ctx, "root", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := auth.ParseToken(tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Role != auth.RoleAdmin {
+		t.Fatalf("role = %q, want admin", claims.Role)
+	}
+}
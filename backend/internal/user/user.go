@@ -1,7 +1,10 @@
 package user
 
+import "igropoisk_backend/internal/auth"
+
 type User struct {
-	ID           int    `json:"id"`
-	Name         string `json:"name"`
-	PasswordHash string `json:"-"`
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	Role         auth.Role `json:"role"`
+	PasswordHash string    `json:"-"`
 }
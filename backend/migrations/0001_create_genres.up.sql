@@ -1,4 +1,4 @@
-CREATE TABLE genres(
+CREATE TABLE genres (
     id   SERIAL PRIMARY KEY,
     name TEXT NOT NULL UNIQUE
-);
\ No newline at end of file
+);
@@ -0,0 +1,11 @@
+CREATE TABLE games (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    avg_rating NUMERIC(4,2) DEFAULT NULL,
+    reviews_count INT NOT NULL DEFAULT 0,
+    description TEXT,
+    image_url TEXT NOT NULL,
+    genre_id INT NOT NULL REFERENCES genres(id)
+);
+
+CREATE INDEX games_genre_id_idx ON games (genre_id);
@@ -0,0 +1,9 @@
+CREATE TABLE reviews (
+    id SERIAL PRIMARY KEY,
+    game_id INT NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rating INT NOT NULL,
+    description TEXT,
+    CHECK (rating >= 0 AND rating <= 10),
+    UNIQUE (game_id, user_id)
+);
@@ -0,0 +1,3 @@
+DROP TRIGGER trg_update_rating ON reviews;
+DROP FUNCTION update_game_rating();
+DROP FUNCTION recompute_game_rating(INT);
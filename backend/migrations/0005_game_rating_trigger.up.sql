@@ -0,0 +1,33 @@
+CREATE OR REPLACE FUNCTION recompute_game_rating(target_game_id INT) RETURNS void AS $$
+BEGIN
+UPDATE games
+SET
+    reviews_count = sub.count,
+    avg_rating = CASE WHEN sub.count >= 3 THEN sub.avg ELSE NULL END
+    FROM (
+        SELECT COUNT(*) AS count, AVG(rating) AS avg
+        FROM reviews
+        WHERE game_id = target_game_id
+    ) AS sub
+WHERE games.id = target_game_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- NEW is null on DELETE and OLD is null on INSERT; an UPDATE may move a review between games
+CREATE OR REPLACE FUNCTION update_game_rating() RETURNS trigger AS $$
+BEGIN
+IF TG_OP IN ('INSERT', 'UPDATE') THEN
+    PERFORM recompute_game_rating(NEW.game_id);
+END IF;
+IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND OLD.game_id IS DISTINCT FROM NEW.game_id) THEN
+    PERFORM recompute_game_rating(OLD.game_id);
+END IF;
+
+RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_update_rating
+AFTER INSERT OR UPDATE OR DELETE ON reviews
+FOR EACH ROW
+EXECUTE FUNCTION update_game_rating();
@@ -0,0 +1,12 @@
+CREATE TABLE search_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    game_id INT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('index', 'delete')),
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    processed_at TIMESTAMP WITH TIME ZONE DEFAULT NULL
+);
+
+CREATE INDEX search_outbox_pending_idx ON search_outbox (id) WHERE processed_at IS NULL;
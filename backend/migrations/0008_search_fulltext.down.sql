@@ -0,0 +1,3 @@
+DROP INDEX games_name_trgm_idx;
+DROP INDEX games_search_vector_idx;
+ALTER TABLE games DROP COLUMN search_vector;
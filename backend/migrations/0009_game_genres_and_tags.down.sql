@@ -0,0 +1,12 @@
+DROP VIEW game_details;
+
+DROP TABLE game_tags;
+DROP TABLE tags;
+
+-- a game keeps one of its genres, games without any cannot be migrated down
+ALTER TABLE games ADD COLUMN genre_id INT REFERENCES genres(id);
+UPDATE games SET genre_id = (SELECT MIN(genre_id) FROM game_genres WHERE game_id = games.id);
+ALTER TABLE games ALTER COLUMN genre_id SET NOT NULL;
+CREATE INDEX games_genre_id_idx ON games (genre_id);
+
+DROP TABLE game_genres;
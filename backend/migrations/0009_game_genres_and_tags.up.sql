@@ -0,0 +1,48 @@
+CREATE TABLE game_genres (
+    game_id  INT NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+    genre_id INT NOT NULL REFERENCES genres(id),
+    PRIMARY KEY (game_id, genre_id)
+);
+
+CREATE INDEX game_genres_genre_id_idx ON game_genres (genre_id);
+
+INSERT INTO game_genres (game_id, genre_id) SELECT id, genre_id FROM games;
+
+ALTER TABLE games DROP COLUMN genre_id;
+
+CREATE TABLE tags (
+    id   SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE game_tags (
+    game_id INT NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+    tag_id  INT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (game_id, tag_id)
+);
+
+CREATE INDEX game_tags_tag_id_idx ON game_tags (tag_id);
+
+-- games as the API returns them, with their genres and tags as JSON arrays
+CREATE VIEW game_details AS
+SELECT
+    game.id,
+    game.name,
+    game.avg_rating,
+    game.reviews_count,
+    game.description,
+    game.image_url,
+    COALESCE((
+        SELECT jsonb_agg(jsonb_build_object('id', ge.id, 'name', ge.name) ORDER BY ge.name)
+        FROM game_genres gg
+                 JOIN genres ge ON ge.id = gg.genre_id
+        WHERE gg.game_id = game.id
+    ), '[]') AS genres,
+    COALESCE((
+        SELECT jsonb_agg(jsonb_build_object('id', t.id, 'name', t.name) ORDER BY t.name)
+        FROM game_tags gt
+                 JOIN tags t ON t.id = gt.tag_id
+        WHERE gt.game_id = game.id
+    ), '[]') AS tags,
+    game.search_vector
+FROM games game;
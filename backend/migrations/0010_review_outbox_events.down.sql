@@ -0,0 +1,12 @@
+CREATE OR REPLACE FUNCTION update_game_rating() RETURNS trigger AS $$
+BEGIN
+IF TG_OP IN ('INSERT', 'UPDATE') THEN
+    PERFORM recompute_game_rating(NEW.game_id);
+END IF;
+IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND OLD.game_id IS DISTINCT FROM NEW.game_id) THEN
+    PERFORM recompute_game_rating(OLD.game_id);
+END IF;
+
+RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
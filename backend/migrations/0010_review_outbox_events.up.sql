@@ -0,0 +1,15 @@
+-- a review changes its game's rating and reviews_count, which are indexed for search
+CREATE OR REPLACE FUNCTION update_game_rating() RETURNS trigger AS $$
+BEGIN
+IF TG_OP IN ('INSERT', 'UPDATE') THEN
+    PERFORM recompute_game_rating(NEW.game_id);
+    INSERT INTO search_outbox (game_id, action) VALUES (NEW.game_id, 'index');
+END IF;
+IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND OLD.game_id IS DISTINCT FROM NEW.game_id) THEN
+    PERFORM recompute_game_rating(OLD.game_id);
+    INSERT INTO search_outbox (game_id, action) VALUES (OLD.game_id, 'index');
+END IF;
+
+RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
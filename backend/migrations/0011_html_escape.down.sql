@@ -0,0 +1 @@
+DROP FUNCTION IF EXISTS html_escape(text);
@@ -0,0 +1,6 @@
+-- html_escape makes user text safe to wrap in ts_headline highlight tags; the
+-- text search parser skips the entities it introduces.
+CREATE OR REPLACE FUNCTION html_escape(s text) RETURNS text AS $$
+SELECT replace(replace(replace(replace(replace(s,
+    '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')
+$$ LANGUAGE sql IMMUTABLE STRICT;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS games_name_key;
@@ -0,0 +1,6 @@
+-- names were only kept distinct by the application; later copies get their id appended
+UPDATE games dup
+SET name = dup.name || ' (' || dup.id || ')'
+WHERE EXISTS (SELECT 1 FROM games first WHERE first.name = dup.name AND first.id < dup.id);
+
+CREATE UNIQUE INDEX games_name_key ON games (name);
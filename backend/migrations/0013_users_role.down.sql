@@ -0,0 +1,2 @@
+-- role belongs to 0003_create_users, which reverts it
+SELECT 1;
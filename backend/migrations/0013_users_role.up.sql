@@ -0,0 +1,4 @@
+-- 0003 creates role with the table, but databases baselined from the old schema files
+-- may predate the column
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'moderator', 'admin'));
@@ -0,0 +1,8 @@
+// Package migrations embeds the database schema as numbered up/down SQL files,
+// "<version>_<name>.up.sql" and "<version>_<name>.down.sql", applied by internal/db/migrate.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS